@@ -1,9 +1,11 @@
 package config
 
 import (
+	"crypto/rsa"
 	"fmt"
 	"os"
 
+	"github.com/farellandr/spoticket/internal/helpers"
 	"github.com/farellandr/spoticket/internal/models"
 	"github.com/xendit/xendit-go/v6"
 	"gorm.io/driver/postgres"
@@ -46,10 +48,250 @@ func InitXenditClient(config *XenditConfig) (*xendit.APIClient, error) {
 	return client, nil
 }
 
+type WalletConfig struct {
+	PassTypeIdentifier  string
+	TeamIdentifier      string
+	OrganizationName    string
+	PassCertificatePath string
+	PassKeyPath         string
+	WWDRCertificatePath string
+
+	GoogleIssuerID            string
+	GoogleServiceAccountEmail string
+	GoogleKeyPath             string
+}
+
+func LoadWalletConfig() (*WalletConfig, error) {
+	return &WalletConfig{
+		PassTypeIdentifier:  os.Getenv("APPLE_PASS_TYPE_ID"),
+		TeamIdentifier:      os.Getenv("APPLE_TEAM_ID"),
+		OrganizationName:    os.Getenv("APPLE_PASS_ORGANIZATION"),
+		PassCertificatePath: os.Getenv("APPLE_PASS_CERT_PATH"),
+		PassKeyPath:         os.Getenv("APPLE_PASS_KEY_PATH"),
+		WWDRCertificatePath: os.Getenv("APPLE_WWDR_CERT_PATH"),
+
+		GoogleIssuerID:            os.Getenv("GOOGLE_WALLET_ISSUER_ID"),
+		GoogleServiceAccountEmail: os.Getenv("GOOGLE_WALLET_SERVICE_ACCOUNT_EMAIL"),
+		GoogleKeyPath:             os.Getenv("GOOGLE_WALLET_KEY_PATH"),
+	}, nil
+}
+
+func InitWalletIssuer(config *WalletConfig) (*helpers.WalletIssuer, error) {
+	issuer := &helpers.WalletIssuer{
+		PassTypeIdentifier:        config.PassTypeIdentifier,
+		TeamIdentifier:            config.TeamIdentifier,
+		OrganizationName:          config.OrganizationName,
+		GoogleIssuerID:            config.GoogleIssuerID,
+		GoogleServiceAccountEmail: config.GoogleServiceAccountEmail,
+	}
+
+	if config.PassCertificatePath != "" {
+		signer, err := helpers.LoadPassSigner(config.PassCertificatePath, config.PassKeyPath, config.WWDRCertificatePath)
+		if err != nil {
+			return nil, err
+		}
+		issuer.AppleSigner = signer
+	}
+
+	if config.GoogleKeyPath != "" {
+		keyPEM, err := os.ReadFile(config.GoogleKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		key, err := helpers.ParsePrivateKeyPEM(keyPEM)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("google wallet key must be an RSA key")
+		}
+		issuer.GoogleKey = rsaKey
+	}
+
+	return issuer, nil
+}
+
+type SMSConfig struct {
+	GatewayURL   string
+	GatewayToken string
+}
+
+func LoadSMSConfig() (*SMSConfig, error) {
+	return &SMSConfig{
+		GatewayURL:   os.Getenv("SMS_GATEWAY_URL"),
+		GatewayToken: os.Getenv("SMS_GATEWAY_TOKEN"),
+	}, nil
+}
+
+// InitSMSSender returns nil when no gateway is configured, which disables
+// phone verification.
+func InitSMSSender(config *SMSConfig) (*helpers.SMSSender, error) {
+	if config.GatewayURL == "" {
+		return nil, nil
+	}
+	return helpers.NewSMSSender(config.GatewayURL, config.GatewayToken), nil
+}
+
 func enableUUIDExtension(db *gorm.DB) error {
 	return db.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\"").Error
 }
 
+// eventSearchMigrations keeps events.search_vector in sync with the title,
+// description, location and category names, stemmed in both English and
+// Indonesian.
+var eventSearchMigrations = []string{
+	`ALTER TABLE events ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+	`CREATE INDEX IF NOT EXISTS idx_events_search_vector ON events USING GIN (search_vector)`,
+	`CREATE OR REPLACE FUNCTION events_search_document(event_id uuid, title text, description text, location text) RETURNS tsvector AS $$
+	DECLARE
+		category_names text;
+	BEGIN
+		SELECT COALESCE(string_agg(categories.name, ' '), '') INTO category_names
+		FROM event_categories JOIN categories ON categories.id = event_categories.category_id
+		WHERE event_categories.event_id = events_search_document.event_id;
+
+		RETURN setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+			setweight(to_tsvector('indonesian', COALESCE(title, '')), 'A') ||
+			setweight(to_tsvector('english', category_names), 'B') ||
+			setweight(to_tsvector('indonesian', category_names), 'B') ||
+			setweight(to_tsvector('english', COALESCE(location, '')), 'B') ||
+			setweight(to_tsvector('indonesian', COALESCE(location, '')), 'B') ||
+			setweight(to_tsvector('english', COALESCE(description, '')), 'C') ||
+			setweight(to_tsvector('indonesian', COALESCE(description, '')), 'C');
+	END
+	$$ LANGUAGE plpgsql STABLE`,
+	`CREATE OR REPLACE FUNCTION events_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		NEW.search_vector := events_search_document(NEW.id, NEW.title, NEW.description, NEW.location);
+		RETURN NEW;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS events_search_vector_update ON events`,
+	`CREATE TRIGGER events_search_vector_update BEFORE INSERT OR UPDATE OF title, description, location ON events
+	FOR EACH ROW EXECUTE FUNCTION events_search_vector_trigger()`,
+	`CREATE OR REPLACE FUNCTION event_categories_search_vector_trigger() RETURNS trigger AS $$
+	DECLARE
+		affected uuid;
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			affected := OLD.event_id;
+		ELSE
+			affected := NEW.event_id;
+		END IF;
+
+		UPDATE events SET search_vector = events_search_document(id, title, description, location) WHERE id = affected;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS event_categories_search_vector_update ON event_categories`,
+	`CREATE TRIGGER event_categories_search_vector_update AFTER INSERT OR DELETE ON event_categories
+	FOR EACH ROW EXECUTE FUNCTION event_categories_search_vector_trigger()`,
+	`CREATE OR REPLACE FUNCTION categories_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		UPDATE events SET search_vector = events_search_document(events.id, events.title, events.description, events.location)
+		FROM event_categories
+		WHERE event_categories.event_id = events.id AND event_categories.category_id = NEW.id;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS categories_search_vector_update ON categories`,
+	`CREATE TRIGGER categories_search_vector_update AFTER UPDATE OF name ON categories
+	FOR EACH ROW EXECUTE FUNCTION categories_search_vector_trigger()`,
+	`UPDATE events SET search_vector = events_search_document(id, title, description, location) WHERE search_vector IS NULL`,
+}
+
+func migrateEventSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range eventSearchMigrations {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateEventStatus moves events cancelled before statuses existed out of the
+// published status the column default gives existing rows.
+func migrateEventStatus(db *gorm.DB) error {
+	return db.Model(&models.Event{}).
+		Where("cancelled_at IS NOT NULL AND status = ?", models.EventStatusPublished).
+		Update("status", models.EventStatusCancelled).Error
+}
+
+// migrateCouponOwnership keeps coupons created before ownership existed
+// usable on every event.
+func migrateCouponOwnership(db *gorm.DB) error {
+	return db.Model(&models.Coupon{}).
+		Where("user_id IS NULL AND is_platform = ?", false).
+		Update("is_platform", true).Error
+}
+
+// runOnce applies a data migration the first time the service starts against
+// a database. Applied migrations are recorded in schema_migrations in the
+// same transaction, so concurrent startups can't both apply one.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		name text PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`INSERT INTO schema_migrations (name) VALUES (?) ON CONFLICT DO NOTHING`, name)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return migrate(tx)
+	})
+}
+
+// dedupeUserCoupons removes repeated claims of a coupon by the same user so
+// the unique claim index can be created, keeping a used claim if there is one.
+func dedupeUserCoupons(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.UserCoupon{}) {
+		return nil
+	}
+	return db.Exec(`DELETE FROM user_coupons a USING user_coupons b
+		WHERE a.user_id = b.user_id AND a.coupon_id = b.coupon_id
+		AND (a.is_used < b.is_used OR (a.is_used = b.is_used AND a.ctid > b.ctid))`).Error
+}
+
+// addCouponStackable adds coupons.stackable to tables created before it
+// existed. The model has no default, so without one here the column couldn't
+// be added to a table that already has coupons.
+func addCouponStackable(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Coupon{}) {
+		return nil
+	}
+	return db.Exec(`ALTER TABLE coupons ADD COLUMN IF NOT EXISTS stackable boolean NOT NULL DEFAULT true`).Error
+}
+
+// migrateCouponCounters fills the claim and redemption counters of coupons
+// claimed before the counters existed. Uses are counted from paid payments.
+func migrateCouponCounters(db *gorm.DB) error {
+	err := db.Exec(`UPDATE user_coupons SET used_count = (SELECT COUNT(*) FROM payments
+		WHERE payments.user_id = user_coupons.user_id AND payments.coupon_id = user_coupons.coupon_id
+		AND payments.status = 'PAID' AND payments.deleted_at IS NULL)`).Error
+	if err != nil {
+		return err
+	}
+	err = db.Exec(`UPDATE user_coupons SET is_used = used_count >= coupons.max_uses_per_user
+		FROM coupons WHERE coupons.id = user_coupons.coupon_id`).Error
+	if err != nil {
+		return err
+	}
+	return db.Exec(`UPDATE coupons SET
+		claim_count = (SELECT COUNT(*) FROM user_coupons WHERE user_coupons.coupon_id = coupons.id),
+		redemption_count = (SELECT COUNT(*) FROM payments WHERE payments.coupon_id = coupons.id AND payments.status = 'PAID' AND payments.deleted_at IS NULL)`).Error
+}
+
 func InitDatabase(cfg *Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
@@ -65,11 +307,35 @@ func InitDatabase(cfg *Config) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.Event{}, &models.Ticket{}, &models.Purchase{}, &models.Payment{}, &models.Category{}, &models.Coupon{}, &models.UserCoupon{})
+	if err := runOnce(db, "dedupe_user_coupons", dedupeUserCoupons); err != nil {
+		return nil, err
+	}
+
+	if err := addCouponStackable(db); err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.Role{}, &models.User{}, &models.Event{}, &models.Ticket{}, &models.Purchase{}, &models.Payment{}, &models.Category{}, &models.Coupon{}, &models.UserCoupon{}, &models.CheckIn{}, &models.Transfer{}, &models.Attendee{}, &models.ResaleListing{}, &models.Venue{}, &models.VenuePhoto{}, &models.EventSeries{}, &models.SeriesTicketTemplate{}, &models.SeatSection{}, &models.Seat{}, &models.PhoneVerification{}, &models.WaitingRoom{}, &models.QueueEntry{}, &models.WaitlistEntry{}, &models.AccessCode{}, &models.Product{}, &models.ProductVariant{}, &models.AddonOrderItem{}, &models.AddonVoucher{}, &models.PricingRule{})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := migrateEventSearch(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateEventStatus(db); err != nil {
+		return nil, err
+	}
+
+	if err := runOnce(db, "coupon_ownership", migrateCouponOwnership); err != nil {
+		return nil, err
+	}
+
+	if err := runOnce(db, "coupon_counters", migrateCouponCounters); err != nil {
+		return nil, err
+	}
+
 	seedRoles(db)
 
 	return db, nil
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	codeAccessCodeRequired  = "access_code_required"
+	codeInvalidAccessCode   = "invalid_access_code"
+	codeAccessCodeExhausted = "access_code_exhausted"
+	codeAccessCodeUserLimit = "access_code_user_limit"
+)
+
+var accessCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// accessCodeUsesSQL counts the orders placed with an access code.
+const accessCodeUsesSQL = "(SELECT COUNT(*) FROM payments WHERE payments.access_code_id = access_codes.id AND payments.status IN ('PENDING', 'PAID') AND payments.deleted_at IS NULL) AS uses"
+
+type AccessCodeRequest struct {
+	Code           string      `json:"code" binding:"required"`
+	TicketIDs      []uuid.UUID `json:"ticket_ids" binding:"required,min=1"`
+	ValidFrom      *time.Time  `json:"valid_from"`
+	ValidUntil     *time.Time  `json:"valid_until"`
+	MaxUses        int         `json:"max_uses"`
+	MaxUsesPerUser int         `json:"max_uses_per_user"`
+}
+
+func normalizeAccessCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validateVisibility(req *TicketRequest) string {
+	switch req.Visibility {
+	case "":
+		req.Visibility = models.TicketVisibilityPublic
+	case models.TicketVisibilityPublic, models.TicketVisibilityHidden, models.TicketVisibilityLocked:
+	default:
+		return "Invalid visibility."
+	}
+	return ""
+}
+
+// bindAccessCode validates an access code request against the event and
+// returns the tickets it unlocks.
+func bindAccessCode(c *gin.Context, gormDB *gorm.DB, event *models.Event, codeID *uuid.UUID) (*AccessCodeRequest, []models.Ticket, bool) {
+	var req AccessCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return nil, nil, false
+	}
+
+	req.Code = normalizeAccessCode(req.Code)
+	if !accessCodePattern.MatchString(req.Code) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Code must be 3 to 32 letters, digits, dashes or underscores.")
+		return nil, nil, false
+	}
+	if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Valid until must be after valid from.")
+		return nil, nil, false
+	}
+	if req.MaxUses < 0 || req.MaxUsesPerUser < 0 {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Usage limits must not be negative.")
+		return nil, nil, false
+	}
+
+	duplicate := gormDB.Model(&models.AccessCode{}).Where("event_id = ? AND code = ?", event.ID, req.Code)
+	if codeID != nil {
+		duplicate = duplicate.Where("id <> ?", *codeID)
+	}
+	var count int64
+	duplicate.Count(&count)
+	if count > 0 {
+		helpers.RespondWithError(c, http.StatusConflict, "This event already has that access code.")
+		return nil, nil, false
+	}
+
+	var tickets []models.Ticket
+	if err := gormDB.Where("id IN ? AND event_id = ?", req.TicketIDs, event.ID).Find(&tickets).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving tickets.")
+		return nil, nil, false
+	}
+	if len(tickets) != len(req.TicketIDs) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Every ticket must belong to this event.")
+		return nil, nil, false
+	}
+
+	return &req, tickets, true
+}
+
+func CreateAccessCode(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	req, tickets, ok := bindAccessCode(c, gormDB, event, nil)
+	if !ok {
+		return
+	}
+
+	accessCode := models.AccessCode{
+		ID:             uuid.New(),
+		EventID:        event.ID,
+		Code:           req.Code,
+		Tickets:        tickets,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+	}
+	if err := gormDB.Create(&accessCode).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create access code.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Access code created successfully.",
+		"access_code_id": accessCode.ID,
+	})
+}
+
+func ListAccessCodes(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var accessCodes []models.AccessCode
+	err := gormDB.Select("access_codes.*, "+accessCodeUsesSQL).Preload("Tickets").
+		Where("event_id = ?", event.ID).Order("created_at DESC").Find(&accessCodes).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving access codes.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_codes": accessCodes,
+	})
+}
+
+func UpdateAccessCode(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var accessCode models.AccessCode
+	if err := gormDB.Where("id = ? AND event_id = ?", c.Param("codeId"), event.ID).First(&accessCode).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Access code not found.")
+		return
+	}
+
+	req, tickets, ok := bindAccessCode(c, gormDB, event, &accessCode.ID)
+	if !ok {
+		return
+	}
+
+	accessCode.Code = req.Code
+	accessCode.ValidFrom = req.ValidFrom
+	accessCode.ValidUntil = req.ValidUntil
+	accessCode.MaxUses = req.MaxUses
+	accessCode.MaxUsesPerUser = req.MaxUsesPerUser
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&accessCode).Error; err != nil {
+			return err
+		}
+		return tx.Model(&accessCode).Association("Tickets").Replace(tickets)
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update access code.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access code updated successfully.",
+	})
+}
+
+func DeleteAccessCode(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	result := gormDB.Where("id = ? AND event_id = ?", c.Param("codeId"), event.ID).Delete(&models.AccessCode{})
+	if result.Error != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to delete access code.")
+		return
+	}
+	if result.RowsAffected == 0 {
+		helpers.RespondWithError(c, http.StatusNotFound, "Access code not found.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access code deleted successfully.",
+	})
+}
+
+// findActiveAccessCode looks up an event's access code that is inside its
+// validity window, with the tickets it unlocks.
+func findActiveAccessCode(gormDB *gorm.DB, eventID uuid.UUID, code string) (*models.AccessCode, error) {
+	now := time.Now()
+
+	var accessCode models.AccessCode
+	err := gormDB.Select("access_codes.*, "+accessCodeUsesSQL).Preload("Tickets").
+		Where("event_id = ? AND code = ?", eventID, normalizeAccessCode(code)).
+		Where("(valid_from IS NULL OR valid_from <= ?) AND (valid_until IS NULL OR valid_until > ?)", now, now).
+		First(&accessCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &accessCode, nil
+}
+
+// unlockedTicketIDs returns the tickets an access code unlocks, or an empty
+// set when the code is missing or not active.
+func unlockedTicketIDs(gormDB *gorm.DB, eventID uuid.UUID, code string) map[uuid.UUID]bool {
+	unlocked := map[uuid.UUID]bool{}
+	if code == "" {
+		return unlocked
+	}
+	accessCode, err := findActiveAccessCode(gormDB, eventID, code)
+	if err != nil {
+		return unlocked
+	}
+	for _, ticket := range accessCode.Tickets {
+		unlocked[ticket.ID] = true
+	}
+	return unlocked
+}
+
+// visibleTickets drops hidden tiers that the access code didn't unlock.
+func visibleTickets(tickets []models.Ticket, unlocked map[uuid.UUID]bool) []models.Ticket {
+	visible := make([]models.Ticket, 0, len(tickets))
+	for _, ticket := range tickets {
+		if ticket.Visibility != models.TicketVisibilityHidden || unlocked[ticket.ID] {
+			visible = append(visible, ticket)
+		}
+	}
+	return visible
+}
+
+// checkTicketAccess requires a usable access code for hidden and locked tiers
+// and returns the ID of the code to record on the payment.
+func checkTicketAccess(gormDB *gorm.DB, ticket *models.Ticket, userID uuid.UUID, code string) (*uuid.UUID, *checkoutError) {
+	if ticket.Visibility == "" || ticket.Visibility == models.TicketVisibilityPublic {
+		return nil, nil
+	}
+	if code == "" {
+		return nil, &checkoutError{http.StatusForbidden, codeAccessCodeRequired, "This ticket requires an access code."}
+	}
+
+	accessCode, err := findActiveAccessCode(gormDB, ticket.EventID, code)
+	if err != nil {
+		return nil, &checkoutError{http.StatusForbidden, codeInvalidAccessCode, "Access code is invalid or not active."}
+	}
+
+	unlocks := false
+	for _, unlocked := range accessCode.Tickets {
+		if unlocked.ID == ticket.ID {
+			unlocks = true
+			break
+		}
+	}
+	if !unlocks {
+		return nil, &checkoutError{http.StatusForbidden, codeInvalidAccessCode, "Access code does not unlock this ticket."}
+	}
+
+	if accessCode.MaxUses > 0 && accessCode.Uses >= int64(accessCode.MaxUses) {
+		return nil, &checkoutError{http.StatusForbidden, codeAccessCodeExhausted, "Access code has reached its usage limit."}
+	}
+
+	if accessCode.MaxUsesPerUser > 0 {
+		var used int64
+		err := gormDB.Model(&models.Payment{}).
+			Where("access_code_id = ? AND user_id = ? AND status IN ?", accessCode.ID, userID, activePaymentStatuses).
+			Count(&used).Error
+		if err != nil {
+			return nil, &checkoutError{status: http.StatusInternalServerError, message: "Error checking access code usage."}
+		}
+		if used >= int64(accessCode.MaxUsesPerUser) {
+			return nil, &checkoutError{http.StatusForbidden, codeAccessCodeUserLimit, "You have already used this access code the maximum number of times."}
+		}
+	}
+
+	return &accessCode.ID, nil
+}
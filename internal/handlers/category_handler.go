@@ -44,6 +44,12 @@ func CreateCategory(c *gin.Context) {
 	})
 }
 
+var categorySortKeys = map[string][]helpers.SortKey{
+	"newest":  {{Column: "categories.created_at", Desc: true}},
+	"name":    {{Column: "categories.name"}},
+	"popular": {{Column: "(SELECT COUNT(*) FROM event_categories JOIN events ON events.id = event_categories.event_id WHERE event_categories.category_id = categories.id AND events.deleted_at IS NULL)", Desc: true}},
+}
+
 func ListCategories(c *gin.Context) {
 	db, exists := c.Get("db")
 	if !exists {
@@ -52,40 +58,43 @@ func ListCategories(c *gin.Context) {
 	}
 	gormDB := db.(*gorm.DB)
 
-	page := c.DefaultQuery("page", "1")
-	limit := c.DefaultQuery("limit", "10")
-
-	pageNum, err := helpers.StringToInt(page)
+	pagination, err := helpers.ParsePagination(c)
 	if err != nil {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid page number.")
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	limitNum, err := helpers.StringToInt(limit)
-	if err != nil {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid limit.")
+	filters := helpers.NewQueryFilters(c)
+	name := filters.String("name")
+	sortKeys := filters.Sort(categorySortKeys)
+	if err := filters.Err(); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if sortKeys == nil {
+		sortKeys = categorySortKeys["newest"]
+	}
 
 	query := gormDB.Model(&models.Category{})
-	var totalCount int64
-	query.Count(&totalCount)
+	if name != "" {
+		query = query.Where("name ILIKE ?", "%"+name+"%")
+	}
 
-	var categories []models.Category
-	offset := (pageNum - 1) * limitNum
-	err = query.Offset(offset).Limit(limitNum).Order("created_at DESC").Find(&categories).Error
+	result, err := pagination.Fetch(query, "categories.id", sortKeys, nil)
 	if err != nil {
+		helpers.RespondWithPageError(c, err, "Error retrieving categories.")
+		return
+	}
+
+	var categories []models.Category
+	if err := gormDB.Where("id IN ?", result.IDs).Find(&categories).Error; err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving categories.")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"categories":  categories,
-		"total":       totalCount,
-		"page":        pageNum,
-		"limit":       limitNum,
-		"total_pages": (totalCount + int64(limitNum) - 1) / int64(limitNum),
-	})
+	response := pagination.Response(result)
+	response["categories"] = helpers.SortByIDs(categories, result.IDs, func(category *models.Category) uuid.UUID { return category.ID })
+	c.JSON(http.StatusOK, response)
 }
 
 func UpdateCategory(c *gin.Context) {
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/middleware"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type tierCheckInStats struct {
+	TicketID  uuid.UUID `json:"ticket_id"`
+	Type      string    `json:"type"`
+	Limit     int       `json:"limit"`
+	Sold      int64     `json:"sold"`
+	CheckedIn int64     `json:"checked_in"`
+	Inside    int64     `json:"inside"`
+}
+
+type arrivalBucket struct {
+	Bucket   time.Time `json:"bucket"`
+	Arrivals int64     `json:"arrivals"`
+}
+
+type gateThroughput struct {
+	Gate           string    `json:"gate"`
+	Scans          int64     `json:"scans"`
+	Entries        int64     `json:"entries"`
+	Exits          int64     `json:"exits"`
+	FirstScan      time.Time `json:"first_scan"`
+	LastScan       time.Time `json:"last_scan"`
+	ScansPerMinute float64   `json:"scans_per_minute" gorm:"-"`
+}
+
+func findOrganizerEvent(c *gin.Context, gormDB *gorm.DB) (*models.Event, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return nil, false
+	}
+
+	var event models.Event
+	if err := gormDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&event).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusForbidden, "Event not found or you don't have permission to view it.")
+			return nil, false
+		}
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving event.")
+		return nil, false
+	}
+
+	return &event, true
+}
+
+func GetCheckInStats(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var tiers []tierCheckInStats
+	err := gormDB.Table("tickets").
+		Select(`tickets.id AS ticket_id, tickets.type, tickets."limit",
+			COUNT(purchases.id) AS sold,
+			COUNT(purchases.id) FILTER (WHERE purchases.entry_count > 0) AS checked_in,
+			COUNT(purchases.id) FILTER (WHERE purchases.is_inside) AS inside`).
+		Joins("LEFT JOIN purchases ON purchases.ticket_id = tickets.id AND purchases.deleted_at IS NULL").
+		Where("tickets.event_id = ? AND tickets.deleted_at IS NULL", event.ID).
+		Group("tickets.id").
+		Order("tickets.price").
+		Scan(&tiers).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving ticket statistics.")
+		return
+	}
+
+	eventCheckIns := gormDB.Table("check_ins").
+		Joins("JOIN purchases ON purchases.id = check_ins.purchase_id").
+		Joins("JOIN tickets ON tickets.id = purchases.ticket_id").
+		Where("tickets.event_id = ?", event.ID)
+
+	var arrivals []arrivalBucket
+	err = eventCheckIns.Session(&gorm.Session{}).
+		Select("to_timestamp(floor(extract(epoch FROM check_ins.created_at) / 300) * 300) AS bucket, COUNT(*) AS arrivals").
+		Where("check_ins.direction = ?", models.CheckInDirectionIn).
+		Group("bucket").
+		Order("bucket").
+		Scan(&arrivals).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving arrival statistics.")
+		return
+	}
+
+	var gates []gateThroughput
+	err = eventCheckIns.Session(&gorm.Session{}).
+		Select(`check_ins.gate AS gate, COUNT(*) AS scans,
+			COUNT(*) FILTER (WHERE check_ins.direction = 'in') AS entries,
+			COUNT(*) FILTER (WHERE check_ins.direction = 'out') AS exits,
+			MIN(check_ins.created_at) AS first_scan, MAX(check_ins.created_at) AS last_scan`).
+		Group("check_ins.gate").
+		Order("check_ins.gate").
+		Scan(&gates).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving gate statistics.")
+		return
+	}
+
+	for i := range gates {
+		minutes := gates[i].LastScan.Sub(gates[i].FirstScan).Minutes()
+		if minutes < 1 {
+			minutes = 1
+		}
+		gates[i].ScansPerMinute = float64(gates[i].Scans) / minutes
+	}
+
+	var totalSold, totalCheckedIn int64
+	for _, tier := range tiers {
+		totalSold += tier.Sold
+		totalCheckedIn += tier.CheckedIn
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id":         event.ID,
+		"sold":             totalSold,
+		"checked_in":       totalCheckedIn,
+		"tiers":            tiers,
+		"arrivals":         arrivals,
+		"bucket_size_secs": 300,
+		"gates":            gates,
+	})
+}
+
+func StreamCheckIns(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	hub := middleware.GetCheckInHub(c)
+	if hub == nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Check-in stream not initialized.")
+		return
+	}
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	scans := hub.Subscribe(event.ID)
+	defer hub.Unsubscribe(event.ID, scans)
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case scan := <-scans:
+			c.SSEvent("checkin", scan)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"time": time.Now()})
+			return true
+		}
+	})
+}
@@ -1,30 +1,230 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/farellandr/spoticket/internal/helpers"
 	"github.com/farellandr/spoticket/internal/models"
+	"github.com/farellandr/spoticket/internal/pricing"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CouponRequest struct {
-	Name        string    `json:"name" binding:"required"`
-	Code        *string   `json:"code"`
-	Limit       int       `json:"limit" binding:"required"`
-	Discount    int       `json:"discount" binding:"required"`
-	ValidAt     time.Time `json:"valid_at" binding:"required"`
-	ExpiredAt   time.Time `json:"expired_at" binding:"required"`
-	Description *string   `json:"description"`
+	Name           string      `json:"name" binding:"required"`
+	Code           *string     `json:"code"`
+	Limit          int         `json:"limit" binding:"required"`
+	Discount       int         `json:"discount"`
+	DiscountType   string      `json:"discount_type"`
+	MaxDiscount    int         `json:"max_discount"`
+	MinOrderAmount int         `json:"min_order_amount"`
+	MinQuantity    int         `json:"min_quantity"`
+	Stackable      *bool       `json:"stackable"`
+	MaxRedemptions int         `json:"max_redemptions"`
+	MaxUsesPerUser int         `json:"max_uses_per_user"`
+	ValidAt        time.Time   `json:"valid_at" binding:"required"`
+	ExpiredAt      time.Time   `json:"expired_at" binding:"required"`
+	Description    *string     `json:"description"`
+	EventIDs       []uuid.UUID `json:"event_ids"`
+	CategoryIDs    []uuid.UUID `json:"category_ids"`
+	TicketIDs      []uuid.UUID `json:"ticket_ids"`
 }
 
 type ClaimCouponRequest struct {
 	Code string `json:"code" binding:"required"`
 }
 
+var (
+	errCouponAlreadyClaimed    = errors.New("coupon already claimed")
+	errCouponClaimLimitReached = errors.New("coupon claim limit reached")
+	errCouponUsedUp            = errors.New("coupon used up by user")
+	errCouponRedemptionsEnded  = errors.New("coupon redemption limit reached")
+)
+
+var couponValidationMessages = map[error]string{
+	pricing.ErrPercentOutOfRange:     "Discount must be between 1 and 100 percent.",
+	pricing.ErrNegativeMaxDiscount:   "Maximum discount must not be negative.",
+	pricing.ErrFixedAmountTooSmall:   "Discount must be at least 1.",
+	pricing.ErrMaxDiscountNotPercent: "Maximum discount only applies to percentage coupons.",
+	pricing.ErrFreeTicketAmount:      "Free ticket coupons don't take a discount amount.",
+	pricing.ErrUnknownDiscountType:   "Discount type must be percentage, fixed_amount or free_ticket.",
+	pricing.ErrNegativeMinimums:      "Minimums must not be negative.",
+	pricing.ErrExpiryBeforeValidity:  "Expiry must be after the start of validity.",
+}
+
+func applyCouponRequest(coupon *models.Coupon, req *CouponRequest) string {
+	if req.Limit < coupon.ClaimCount {
+		return fmt.Sprintf("Limit can't be lower than the %d claims already made.", coupon.ClaimCount)
+	}
+	if req.MaxRedemptions < 0 || (req.MaxRedemptions > 0 && req.MaxRedemptions < coupon.RedemptionCount) {
+		return fmt.Sprintf("Maximum redemptions can't be negative or lower than the %d already made.", coupon.RedemptionCount)
+	}
+	if req.MaxUsesPerUser < 0 {
+		return "Maximum uses per user must not be negative."
+	}
+
+	coupon.MaxRedemptions = req.MaxRedemptions
+	coupon.MaxUsesPerUser = max(req.MaxUsesPerUser, 1)
+	coupon.Name = req.Name
+	coupon.Code = req.Code
+	coupon.Limit = req.Limit
+	coupon.Discount = req.Discount
+	coupon.DiscountType = req.DiscountType
+	if coupon.DiscountType == "" {
+		coupon.DiscountType = models.CouponDiscountPercentage
+	}
+	coupon.MaxDiscount = req.MaxDiscount
+	coupon.MinOrderAmount = req.MinOrderAmount
+	coupon.MinQuantity = req.MinQuantity
+	if req.Stackable != nil {
+		coupon.Stackable = *req.Stackable
+	}
+	coupon.ValidAt = req.ValidAt
+	coupon.ExpiredAt = req.ExpiredAt
+	coupon.Description = req.Description
+	if err := pricing.ValidateCoupon(coupon); err != nil {
+		if msg, ok := couponValidationMessages[err]; ok {
+			return msg
+		}
+		return "Invalid coupon."
+	}
+	return ""
+}
+
+type couponScopes struct {
+	events     []models.Event
+	categories []models.Category
+	tickets    []models.Ticket
+}
+
+// couponManager loads the current user, who must be an organizer or admin.
+func couponManager(c *gin.Context, gormDB *gorm.DB) (*models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return nil, false
+	}
+
+	var user models.User
+	if err := gormDB.Preload("Role").First(&user, "id = ?", userID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return nil, false
+	}
+
+	if user.Role.Name != "organizer" && user.Role.Name != "admin" {
+		helpers.RespondWithError(c, http.StatusForbidden, "You have no permission to manage coupons.")
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// findManagedCoupon loads the coupon in the route, which must be owned by the
+// user unless they are an admin.
+func findManagedCoupon(c *gin.Context, gormDB *gorm.DB, user *models.User) (*models.Coupon, bool) {
+	var coupon models.Coupon
+	if err := gormDB.Where("id = ?", c.Param("id")).First(&coupon).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusNotFound, "Coupon not found.")
+			return nil, false
+		}
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error finding coupon.")
+		return nil, false
+	}
+
+	if user.Role.Name != "admin" && (coupon.UserID == nil || *coupon.UserID != user.ID) {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to modify this coupon.")
+		return nil, false
+	}
+
+	return &coupon, true
+}
+
+// bindCouponScopes loads the coupon's scopes. Organizers can only scope
+// coupons to their own events and tiers.
+func bindCouponScopes(c *gin.Context, gormDB *gorm.DB, user *models.User, req *CouponRequest) (*couponScopes, bool) {
+	scopes := &couponScopes{}
+	isAdmin := user.Role.Name == "admin"
+
+	if len(req.EventIDs) > 0 {
+		query := gormDB.Where("id IN ?", req.EventIDs)
+		if !isAdmin {
+			query = query.Where("user_id = ?", user.ID)
+		}
+		if err := query.Find(&scopes.events).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving events.")
+			return nil, false
+		}
+		if len(scopes.events) != len(req.EventIDs) {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Coupons can only be scoped to your own events.")
+			return nil, false
+		}
+	}
+
+	if len(req.CategoryIDs) > 0 {
+		if err := gormDB.Where("id IN ?", req.CategoryIDs).Find(&scopes.categories).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving categories.")
+			return nil, false
+		}
+		if len(scopes.categories) != len(req.CategoryIDs) {
+			helpers.RespondWithError(c, http.StatusBadRequest, "One or more categories were not found.")
+			return nil, false
+		}
+	}
+
+	if len(req.TicketIDs) > 0 {
+		query := gormDB.Where("tickets.id IN ?", req.TicketIDs)
+		if !isAdmin {
+			query = query.Joins("JOIN events ON events.id = tickets.event_id").Where("events.user_id = ?", user.ID)
+		}
+		if err := query.Find(&scopes.tickets).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving tickets.")
+			return nil, false
+		}
+		if len(scopes.tickets) != len(req.TicketIDs) {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Coupons can only be scoped to tickets of your own events.")
+			return nil, false
+		}
+	}
+
+	return scopes, true
+}
+
+// couponApplies reports whether a coupon, with its scopes loaded, can be used
+// on a ticket whose event and categories are loaded.
+func couponApplies(coupon *models.Coupon, ticket *models.Ticket) bool {
+	if !coupon.IsPlatform && (coupon.UserID == nil || *coupon.UserID != ticket.Event.UserID) {
+		return false
+	}
+	if len(coupon.Events) == 0 && len(coupon.Categories) == 0 && len(coupon.Tickets) == 0 {
+		return true
+	}
+
+	for _, event := range coupon.Events {
+		if event.ID == ticket.EventID {
+			return true
+		}
+	}
+	for _, scoped := range coupon.Tickets {
+		if scoped.ID == ticket.ID {
+			return true
+		}
+	}
+	for _, category := range coupon.Categories {
+		for _, eventCategory := range ticket.Event.Categories {
+			if category.ID == eventCategory.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func CreateCoupon(c *gin.Context) {
 	var req CouponRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -44,15 +244,28 @@ func CreateCoupon(c *gin.Context) {
 	}
 	gormDB := db.(*gorm.DB)
 
+	user, ok := couponManager(c, gormDB)
+	if !ok {
+		return
+	}
+
+	scopes, ok := bindCouponScopes(c, gormDB, user, &req)
+	if !ok {
+		return
+	}
+
 	coupon := models.Coupon{
-		ID:          uuid.New(),
-		Name:        req.Name,
-		Code:        req.Code,
-		Limit:       req.Limit,
-		Discount:    req.Discount,
-		ValidAt:     req.ValidAt,
-		ExpiredAt:   req.ExpiredAt,
-		Description: req.Description,
+		ID:         uuid.New(),
+		UserID:     &user.ID,
+		IsPlatform: user.Role.Name == "admin",
+		Stackable:  true,
+		Events:     scopes.events,
+		Categories: scopes.categories,
+		Tickets:    scopes.tickets,
+	}
+	if msg := applyCouponRequest(&coupon, &req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
 	}
 
 	if err := gormDB.Create(&coupon).Error; err != nil {
@@ -106,25 +319,42 @@ func ClaimCoupon(c *gin.Context) {
 		return
 	}
 
-	var usageCount int64
-	gormDB.Model(&models.UserCoupon{}).Where("coupon_id = ?", coupon.ID).Count(&usageCount)
-	if int(usageCount) >= coupon.Limit {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Coupon usage limit reached.")
-		return
-	}
-
-	var existingClaim models.UserCoupon
-	if err := gormDB.Where("user_id = ? AND coupon_id = ?", userUUID, coupon.ID).First(&existingClaim).Error; err == nil {
-		helpers.RespondWithError(c, http.StatusBadRequest, "You have already claimed this coupon.")
-		return
-	}
-
 	userCoupon := models.UserCoupon{
 		UserID:   userUUID,
 		CouponID: coupon.ID,
 		IsUsed:   false,
 	}
-	if err := gormDB.Create(&userCoupon).Error; err != nil {
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		// The claim only counts if it is new, and the counter only moves
+		// while it is under the limit, so concurrent claims can't overshoot.
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userCoupon)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCouponAlreadyClaimed
+		}
+
+		result = tx.Model(&models.Coupon{}).
+			Where(`id = ? AND claim_count < "limit"`, coupon.ID).
+			Update("claim_count", gorm.Expr("claim_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCouponClaimLimitReached
+		}
+		return nil
+	})
+	switch err {
+	case nil:
+	case errCouponAlreadyClaimed:
+		helpers.RespondWithError(c, http.StatusBadRequest, "You have already claimed this coupon.")
+		return
+	case errCouponClaimLimitReached:
+		helpers.RespondWithError(c, http.StatusBadRequest, "Coupon usage limit reached.")
+		return
+	default:
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to claim the coupon.")
 		return
 	}
@@ -132,15 +362,26 @@ func ClaimCoupon(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Coupon claimed successfully.",
 		"coupon": gin.H{
-			"id":         coupon.ID,
-			"name":       coupon.Name,
-			"discount":   coupon.Discount,
-			"valid_at":   coupon.ValidAt,
-			"expired_at": coupon.ExpiredAt,
+			"id":               coupon.ID,
+			"name":             coupon.Name,
+			"discount":         coupon.Discount,
+			"discount_type":    coupon.DiscountType,
+			"max_discount":     coupon.MaxDiscount,
+			"min_order_amount": coupon.MinOrderAmount,
+			"min_quantity":     coupon.MinQuantity,
+			"max_uses":         coupon.MaxUsesPerUser,
+			"valid_at":         coupon.ValidAt,
+			"expired_at":       coupon.ExpiredAt,
 		},
 	})
 }
 
+var couponSortKeys = map[string][]helpers.SortKey{
+	"newest":   {{Column: "coupons.created_at", Desc: true}},
+	"expiring": {{Column: "coupons.expired_at"}},
+	"discount": {{Column: "coupons.discount", Desc: true}},
+}
+
 func ListCoupons(c *gin.Context) {
 	db, exists := c.Get("db")
 	if !exists {
@@ -149,40 +390,66 @@ func ListCoupons(c *gin.Context) {
 	}
 	gormDB := db.(*gorm.DB)
 
-	page := c.DefaultQuery("page", "1")
-	limit := c.DefaultQuery("limit", "10")
-
-	pageNum, err := helpers.StringToInt(page)
+	pagination, err := helpers.ParsePagination(c)
 	if err != nil {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid page number.")
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	limitNum, err := helpers.StringToInt(limit)
-	if err != nil {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid limit.")
+	filters := helpers.NewQueryFilters(c)
+	name := filters.String("name")
+	active := filters.Bool("active")
+	validFrom, validTo := filters.TimeRange("valid_from", "valid_to")
+	minDiscount, maxDiscount := filters.IntRange("min_discount", "max_discount")
+	sortKeys := filters.Sort(couponSortKeys)
+	if err := filters.Err(); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if sortKeys == nil {
+		sortKeys = couponSortKeys["newest"]
+	}
 
 	query := gormDB.Model(&models.Coupon{})
-	var totalCount int64
-	query.Count(&totalCount)
+	if name != "" {
+		query = query.Where("name ILIKE ?", "%"+name+"%")
+	}
+	if active != nil {
+		now := time.Now()
+		if *active {
+			query = query.Where("valid_at <= ? AND expired_at >= ?", now, now)
+		} else {
+			query = query.Where("valid_at > ? OR expired_at < ?", now, now)
+		}
+	}
+	if validFrom != nil {
+		query = query.Where("expired_at >= ?", *validFrom)
+	}
+	if validTo != nil {
+		query = query.Where("valid_at <= ?", *validTo)
+	}
+	if minDiscount != nil {
+		query = query.Where("discount >= ?", *minDiscount)
+	}
+	if maxDiscount != nil {
+		query = query.Where("discount <= ?", *maxDiscount)
+	}
 
-	var coupons []models.Coupon
-	offset := (pageNum - 1) * limitNum
-	err = query.Offset(offset).Limit(limitNum).Order("created_at DESC").Find(&coupons).Error
+	result, err := pagination.Fetch(query, "coupons.id", sortKeys, nil)
 	if err != nil {
+		helpers.RespondWithPageError(c, err, "Error retrieving coupons.")
+		return
+	}
+
+	var coupons []models.Coupon
+	if err := gormDB.Where("id IN ?", result.IDs).Find(&coupons).Error; err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving coupons.")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"coupons":     coupons,
-		"total":       totalCount,
-		"page":        pageNum,
-		"limit":       limitNum,
-		"total_pages": (totalCount + int64(limitNum) - 1) / int64(limitNum),
-	})
+	response := pagination.Response(result)
+	response["coupons"] = helpers.SortByIDs(coupons, result.IDs, func(coupon *models.Coupon) uuid.UUID { return coupon.ID })
+	c.JSON(http.StatusOK, response)
 }
 
 func GetCoupon(c *gin.Context) {
@@ -196,7 +463,7 @@ func GetCoupon(c *gin.Context) {
 	gormDB := db.(*gorm.DB)
 
 	var coupon models.Coupon
-	if err := gormDB.Where("id = ?", couponID).First(&coupon).Error; err != nil {
+	if err := gormDB.Preload("Events").Preload("Categories").Preload("Tickets").Where("id = ?", couponID).First(&coupon).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			helpers.RespondWithError(c, http.StatusNotFound, "Coupon not found.")
 			return
@@ -209,8 +476,6 @@ func GetCoupon(c *gin.Context) {
 }
 
 func UpdateCoupon(c *gin.Context) {
-	couponID := c.Param("id")
-
 	var req CouponRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
@@ -229,25 +494,39 @@ func UpdateCoupon(c *gin.Context) {
 	}
 	gormDB := db.(*gorm.DB)
 
-	var coupon models.Coupon
-	if err := gormDB.Where("id = ?", couponID).First(&coupon).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			helpers.RespondWithError(c, http.StatusForbidden, "Coupon not found.")
-			return
-		}
-		helpers.RespondWithError(c, http.StatusInternalServerError, "Error finding coupon.")
+	user, ok := couponManager(c, gormDB)
+	if !ok {
 		return
 	}
 
-	coupon.Name = req.Name
-	coupon.Code = req.Code
-	coupon.Limit = req.Limit
-	coupon.Discount = req.Discount
-	coupon.ValidAt = req.ValidAt
-	coupon.ExpiredAt = req.ExpiredAt
-	coupon.Description = req.Description
+	coupon, ok := findManagedCoupon(c, gormDB, user)
+	if !ok {
+		return
+	}
+
+	scopes, ok := bindCouponScopes(c, gormDB, user, &req)
+	if !ok {
+		return
+	}
+
+	if msg := applyCouponRequest(coupon, &req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
 
-	if err := gormDB.Save(&coupon).Error; err != nil {
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(coupon).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(coupon).Association("Events").Replace(scopes.events); err != nil {
+			return err
+		}
+		if err := tx.Model(coupon).Association("Categories").Replace(scopes.categories); err != nil {
+			return err
+		}
+		return tx.Model(coupon).Association("Tickets").Replace(scopes.tickets)
+	})
+	if err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update coupon.")
 		return
 	}
@@ -259,8 +538,6 @@ func UpdateCoupon(c *gin.Context) {
 }
 
 func DeleteCoupon(c *gin.Context) {
-	couponID := c.Param("id")
-
 	db, exists := c.Get("db")
 	if !exists {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
@@ -268,14 +545,18 @@ func DeleteCoupon(c *gin.Context) {
 	}
 	gormDB := db.(*gorm.DB)
 
-	result := gormDB.Where("id = ?", couponID).Delete(&models.Coupon{})
-	if result.Error != nil {
-		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to delete coupon.")
+	user, ok := couponManager(c, gormDB)
+	if !ok {
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		helpers.RespondWithError(c, http.StatusForbidden, "Coupon not found or you don't have permission to delete.")
+	coupon, ok := findManagedCoupon(c, gormDB, user)
+	if !ok {
+		return
+	}
+
+	if err := gormDB.Delete(coupon).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to delete coupon.")
 		return
 	}
 
@@ -283,3 +564,109 @@ func DeleteCoupon(c *gin.Context) {
 		"message": "Coupon deleted successfully.",
 	})
 }
+
+// reserveCoupon checks that a checkout can use a claimed coupon. Uses count
+// once paid, so checkouts still awaiting payment are counted against the
+// limits too. The coupon row stays locked until the caller's transaction
+// records its pending payment, so concurrent checkouts can't overuse it.
+func reserveCoupon(tx *gorm.DB, userID, couponID uuid.UUID) error {
+	var coupon models.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, couponID).Error; err != nil {
+		return err
+	}
+
+	var claim models.UserCoupon
+	if err := tx.Where("user_id = ? AND coupon_id = ?", userID, couponID).First(&claim).Error; err != nil {
+		return err
+	}
+
+	var pending, userPending int64
+	err := tx.Model(&models.Payment{}).Where("coupon_id = ? AND status = ?", couponID, "PENDING").Count(&pending).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&models.Payment{}).Where("coupon_id = ? AND user_id = ? AND status = ?", couponID, userID, "PENDING").Count(&userPending).Error
+	if err != nil {
+		return err
+	}
+
+	if claim.UsedCount+int(userPending) >= coupon.MaxUsesPerUser {
+		return errCouponUsedUp
+	}
+	if coupon.MaxRedemptions > 0 && coupon.RedemptionCount+int(pending) >= coupon.MaxRedemptions {
+		return errCouponRedemptionsEnded
+	}
+	return nil
+}
+
+// redeemCoupon counts a use of the coupon once its checkout is paid. The
+// limits were checked when the checkout reserved the coupon, and a paid
+// discount can't be taken back, so the counters move unconditionally.
+func redeemCoupon(tx *gorm.DB, userID, couponID uuid.UUID) error {
+	err := tx.Model(&models.UserCoupon{}).
+		Where("user_id = ? AND coupon_id = ?", userID, couponID).
+		Updates(map[string]interface{}{
+			"used_count": gorm.Expr("used_count + 1"),
+			"is_used":    gorm.Expr("used_count + 1 >= (SELECT max_uses_per_user FROM coupons WHERE coupons.id = ?)", couponID),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.Coupon{}).
+		Where("id = ?", couponID).
+		Update("redemption_count", gorm.Expr("redemption_count + 1")).Error
+}
+
+func ListMyCoupons(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var claims []models.UserCoupon
+	if err := gormDB.Where("user_id = ?", userID).Order("created_at DESC").Find(&claims).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving coupons.")
+		return
+	}
+
+	couponIDs := make([]uuid.UUID, 0, len(claims))
+	for _, claim := range claims {
+		couponIDs = append(couponIDs, claim.CouponID)
+	}
+
+	var coupons []models.Coupon
+	if err := gormDB.Where("id IN ?", couponIDs).Find(&coupons).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving coupons.")
+		return
+	}
+	couponsByID := make(map[uuid.UUID]*models.Coupon, len(coupons))
+	for i := range coupons {
+		couponsByID[coupons[i].ID] = &coupons[i]
+	}
+
+	response := make([]gin.H, 0, len(claims))
+	for _, claim := range claims {
+		coupon, ok := couponsByID[claim.CouponID]
+		if !ok {
+			continue
+		}
+		response = append(response, gin.H{
+			"coupon":         coupon,
+			"used_count":     claim.UsedCount,
+			"remaining_uses": max(coupon.MaxUsesPerUser-claim.UsedCount, 0),
+			"claimed_at":     claim.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coupons": response,
+	})
+}
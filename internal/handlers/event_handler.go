@@ -3,6 +3,8 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/farellandr/spoticket/internal/helpers"
@@ -34,6 +36,40 @@ func CreateEvent(c *gin.Context) {
 	district := c.PostForm("district")
 	subDistrict := c.PostForm("sub_district")
 	location := c.PostForm("location")
+	venueID := c.PostForm("venue_id")
+	transfersDisabled := c.PostForm("transfers_disabled") == "true"
+	resaleEnabled := c.PostForm("resale_enabled") == "true"
+	timezone := c.PostForm("timezone")
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Invalid timezone.")
+			return
+		}
+	}
+
+	latitude, err := parseCoordinate(c.PostForm("latitude"), 90)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid latitude.")
+		return
+	}
+	longitude, err := parseCoordinate(c.PostForm("longitude"), 180)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid longitude.")
+		return
+	}
+	if (latitude == nil) != (longitude == nil) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Latitude and longitude must be provided together.")
+		return
+	}
+
+	resaleMaxMarkup := 0
+	if markupStr := c.PostForm("resale_max_markup"); markupStr != "" {
+		resaleMaxMarkup, err = helpers.StringToInt(markupStr)
+		if err != nil || resaleMaxMarkup < 0 {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Invalid resale max markup.")
+			return
+		}
+	}
 
 	var categories []string
 	for i := 0; ; i++ {
@@ -73,6 +109,15 @@ func CreateEvent(c *gin.Context) {
 		return
 	}
 
+	var venue *models.Venue
+	if venueID != "" {
+		venue = &models.Venue{}
+		if err := gormDB.Where("id = ?", venueID).First(venue).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Venue not found.")
+			return
+		}
+	}
+
 	var eventCategories []models.Category
 	for _, categoryName := range categories {
 		var category models.Category
@@ -84,18 +129,31 @@ func CreateEvent(c *gin.Context) {
 	}
 
 	event := models.Event{
-		ID:          uuid.New(),
-		Title:       title,
-		Description: description,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Province:    province,
-		City:        city,
-		District:    district,
-		SubDistrict: subDistrict,
-		Location:    location,
-		UserID:      user.ID,
-		Categories:  eventCategories,
+		ID:                uuid.New(),
+		Title:             title,
+		Description:       description,
+		StartTime:         startTime,
+		EndTime:           endTime,
+		Province:          province,
+		City:              city,
+		District:          district,
+		SubDistrict:       subDistrict,
+		Location:          location,
+		Latitude:          latitude,
+		Longitude:         longitude,
+		UserID:            user.ID,
+		Categories:        eventCategories,
+		TransfersDisabled: transfersDisabled,
+		ResaleEnabled:     resaleEnabled,
+		ResaleMaxMarkup:   resaleMaxMarkup,
+		Timezone:          timezone,
+		Status:            models.EventStatusDraft,
+	}
+	if event.Timezone == "" {
+		event.Timezone = models.DefaultEventTimezone
+	}
+	if venue != nil {
+		applyVenueLocation(&event, venue)
 	}
 
 	bannerFile, err := c.FormFile("banner")
@@ -108,7 +166,16 @@ func CreateEvent(c *gin.Context) {
 		event.BannerPath = bannerPath
 	}
 
-	if err := gormDB.Create(&event).Error; err != nil {
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		if venue != nil {
+			return copyVenueSeatMap(tx, venue.ID, event.ID)
+		}
+		return nil
+	})
+	if err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create event.")
 		return
 	}
@@ -116,9 +183,23 @@ func CreateEvent(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Event created successfully.",
 		"event_id": event.ID,
+		"status":   event.Status,
 	})
 }
 
+func applyVenueLocation(event *models.Event, venue *models.Venue) {
+	event.VenueID = &venue.ID
+	event.Province = venue.Province
+	event.City = venue.City
+	event.District = venue.District
+	event.SubDistrict = venue.SubDistrict
+	event.Location = venue.Name
+	if venue.Latitude != nil && venue.Longitude != nil {
+		event.Latitude = venue.Latitude
+		event.Longitude = venue.Longitude
+	}
+}
+
 func GetEvent(c *gin.Context) {
 	eventID := c.Param("id")
 
@@ -130,7 +211,8 @@ func GetEvent(c *gin.Context) {
 	gormDB := db.(*gorm.DB)
 
 	var event models.Event
-	if err := gormDB.Preload("Categories").Preload("User").Preload("Tickets.Purchases").Where("id = ?", eventID).First(&event).Error; err != nil {
+	err := eventDetails(gormDB).Where("id = ? AND status IN ?", eventID, publicEventStatuses).First(&event).Error
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			helpers.RespondWithError(c, http.StatusNotFound, "Event not found.")
 			return
@@ -139,9 +221,18 @@ func GetEvent(c *gin.Context) {
 		return
 	}
 
+	if err := applyTicketSaleStatuses(gormDB, &event); err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving ticket availability.")
+		return
+	}
+	event.Tickets = visibleTickets(event.Tickets, unlockedTicketIDs(gormDB, event.ID, c.Query("access_code")))
 	c.JSON(http.StatusOK, event)
 }
 
+func eventDetails(gormDB *gorm.DB) *gorm.DB {
+	return gormDB.Preload("Categories").Preload("User").Preload("Venue.Photos").Preload("Tickets.Purchases")
+}
+
 func StreamEventBanner(c *gin.Context) {
 	eventID := c.Param("id")
 
@@ -170,6 +261,15 @@ func StreamEventBanner(c *gin.Context) {
 	c.File(event.BannerPath)
 }
 
+const maxSearchRadiusKm = 500
+
+var eventSortKeys = map[string][]helpers.SortKey{
+	"soonest":  {{Column: "events.start_time"}},
+	"newest":   {{Column: "events.created_at", Desc: true}},
+	"cheapest": {{Column: "COALESCE((SELECT MIN(tickets.price) FROM tickets WHERE tickets.event_id = events.id AND tickets.visibility <> 'hidden' AND tickets.deleted_at IS NULL), 2147483647)"}},
+	"popular":  {{Column: "(SELECT COUNT(*) FROM purchases JOIN tickets ON tickets.id = purchases.ticket_id WHERE tickets.event_id = events.id AND purchases.deleted_at IS NULL)", Desc: true}},
+}
+
 func ListEvents(c *gin.Context) {
 	db, exists := c.Get("db")
 	if !exists {
@@ -178,26 +278,26 @@ func ListEvents(c *gin.Context) {
 	}
 	gormDB := db.(*gorm.DB)
 
-	page := c.DefaultQuery("page", "1")
-	limit := c.DefaultQuery("limit", "10")
-
-	province := c.Query("province")
-	city := c.Query("city")
-	district := c.Query("district")
+	listEvents(c, gormDB, gormDB.Model(&models.Event{}).Where("events.status IN ?", listedEventStatuses))
+}
 
-	pageNum, err := helpers.StringToInt(page)
+// listEvents applies the shared list filters, search and sorting to query and
+// writes one page of events.
+func listEvents(c *gin.Context, gormDB *gorm.DB, query *gorm.DB) {
+	pagination, err := helpers.ParsePagination(c)
 	if err != nil {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid page number.")
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	limitNum, err := helpers.StringToInt(limit)
-	if err != nil {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid limit.")
-		return
-	}
+	province := c.Query("province")
+	city := c.Query("city")
+	district := c.Query("district")
+	venueID := c.Query("venue_id")
+	lat := c.Query("lat")
+	lng := c.Query("lng")
+	search := strings.TrimSpace(c.Query("q"))
 
-	query := gormDB.Model(&models.Event{})
 	if province != "" {
 		query = query.Where("province = ?", province)
 	}
@@ -207,25 +307,142 @@ func ListEvents(c *gin.Context) {
 	if district != "" {
 		query = query.Where("district = ?", district)
 	}
+	if venueID != "" {
+		query = query.Where("venue_id = ?", venueID)
+	}
 
-	var totalCount int64
-	query.Count(&totalCount)
+	filters := helpers.NewQueryFilters(c)
+	categories := filters.List("category")
+	organizerID := filters.UUID("organizer_id")
+	startFrom, startTo := filters.TimeRange("start_from", "start_to")
+	endFrom, endTo := filters.TimeRange("end_from", "end_to")
+	minPrice, maxPrice := filters.IntRange("min_price", "max_price")
+	available := filters.Bool("available")
+	sortKeys := filters.Sort(eventSortKeys)
+	if err := filters.Err(); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(categories) > 0 {
+		var categoryIDs []uuid.UUID
+		var categoryNames []string
+		for _, category := range categories {
+			if id, err := uuid.Parse(category); err == nil {
+				categoryIDs = append(categoryIDs, id)
+			} else {
+				categoryNames = append(categoryNames, strings.ToLower(category))
+			}
+		}
+		query = query.Where(`EXISTS (SELECT 1 FROM event_categories JOIN categories ON categories.id = event_categories.category_id
+			WHERE event_categories.event_id = events.id AND categories.deleted_at IS NULL
+			AND (categories.id IN ? OR LOWER(categories.name) IN ?))`, categoryIDs, categoryNames)
+	}
+	if organizerID != nil {
+		query = query.Where("events.user_id = ?", *organizerID)
+	}
+	if startFrom != nil {
+		query = query.Where("events.start_time >= ?", *startFrom)
+	}
+	if startTo != nil {
+		query = query.Where("events.start_time <= ?", *startTo)
+	}
+	if endFrom != nil {
+		query = query.Where("events.end_time >= ?", *endFrom)
+	}
+	if endTo != nil {
+		query = query.Where("events.end_time <= ?", *endTo)
+	}
+	if minPrice != nil || maxPrice != nil {
+		priceQuery := gormDB.Model(&models.Ticket{}).Select("1").Where("tickets.event_id = events.id AND tickets.visibility <> ?", models.TicketVisibilityHidden)
+		if minPrice != nil {
+			priceQuery = priceQuery.Where("tickets.price >= ?", *minPrice)
+		}
+		if maxPrice != nil {
+			priceQuery = priceQuery.Where("tickets.price <= ?", *maxPrice)
+		}
+		query = query.Where("EXISTS (?)", priceQuery)
+	}
+	if available != nil {
+		// Sold counts purchases plus tickets held by unpaid checkouts, as
+		// loadPendingQuantities does for sale statuses.
+		availableTickets := gormDB.Model(&models.Ticket{}).Select("1").
+			Where("tickets.event_id = events.id AND tickets.visibility <> ?", models.TicketVisibilityHidden).
+			Where(`tickets."limit" > (SELECT COUNT(*) FROM purchases WHERE purchases.ticket_id = tickets.id AND purchases.deleted_at IS NULL) +
+				(SELECT COALESCE(SUM(payments.quantity), 0) FROM payments WHERE payments.ticket_id = tickets.id AND payments.status = ?
+				AND payments.transaction_id NOT LIKE ? AND payments.deleted_at IS NULL)`, "PENDING", "RSL-%")
+		if *available {
+			query = query.Where("EXISTS (?)", availableTickets)
+		} else {
+			query = query.Where("NOT EXISTS (?)", availableTickets)
+		}
+	}
+
+	params := map[string]interface{}{}
+	selects := []string{"events.*"}
+
+	if search != "" {
+		params["q"] = search
+		query = query.Where("events.search_vector @@ "+helpers.SearchQuerySQL, params)
+		selects = append(selects,
+			helpers.SearchRankSQL("events.search_vector")+" AS rank",
+			helpers.SearchHeadlineSQL("events.title || ' ' || events.description")+" AS highlight",
+		)
+		sortKeys = append(sortKeys, helpers.SortKey{Column: helpers.SearchRankSQL("events.search_vector"), Desc: true})
+	}
+
+	if lat != "" || lng != "" {
+		centreLat, err := parseCoordinate(lat, 90)
+		if err != nil || centreLat == nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Invalid latitude.")
+			return
+		}
+		centreLng, err := parseCoordinate(lng, 180)
+		if err != nil || centreLng == nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Invalid longitude.")
+			return
+		}
+
+		radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "10"), 64)
+		if err != nil || radius <= 0 || radius > maxSearchRadiusKm {
+			helpers.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("Radius must be between 0 and %d km.", maxSearchRadiusKm))
+			return
+		}
+
+		box := helpers.NewBoundingBox(*centreLat, *centreLng, radius)
+		query = query.Where("events.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+		if !box.WrapsLongitude() {
+			query = query.Where("events.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+		}
+
+		params["lat"] = *centreLat
+		params["lng"] = *centreLng
+		params["radius"] = radius
+		distance := helpers.HaversineSQL("events.latitude", "events.longitude")
+		query = query.Where(distance+" <= @radius", params)
+		selects = append(selects, distance+" AS distance")
+		sortKeys = append(sortKeys, helpers.SortKey{Column: distance})
+	}
+
+	sortKeys = append(sortKeys, helpers.SortKey{Column: "events.created_at", Desc: true})
+
+	result, err := pagination.Fetch(query, "events.id", sortKeys, params)
+	if err != nil {
+		helpers.RespondWithPageError(c, err, "Error retrieving events.")
+		return
+	}
 
 	var events []models.Event
-	offset := (pageNum - 1) * limitNum
-	err = query.Preload("Categories").Preload("User").Preload("Tickets").Offset(offset).Limit(limitNum).Order("created_at DESC").Find(&events).Error
+	err = gormDB.Model(&models.Event{}).Select(strings.Join(selects, ", "), params).Where("events.id IN ?", result.IDs).
+		Preload("Categories").Preload("User").Preload("Tickets", "visibility <> ?", models.TicketVisibilityHidden).Find(&events).Error
 	if err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving events.")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"events":      events,
-		"total":       totalCount,
-		"page":        pageNum,
-		"limit":       limitNum,
-		"total_pages": (totalCount + int64(limitNum) - 1) / int64(limitNum),
-	})
+	response := pagination.Response(result)
+	response["events"] = helpers.SortByIDs(events, result.IDs, func(event *models.Event) uuid.UUID { return event.ID })
+	c.JSON(http.StatusOK, response)
 }
 
 func UpdateEvent(c *gin.Context) {
@@ -257,6 +474,40 @@ func UpdateEvent(c *gin.Context) {
 	district := c.PostForm("district")
 	subDistrict := c.PostForm("sub_district")
 	location := c.PostForm("location")
+	venueID := c.PostForm("venue_id")
+	transfersDisabled := c.PostForm("transfers_disabled") == "true"
+	resaleEnabled := c.PostForm("resale_enabled") == "true"
+	timezone := c.PostForm("timezone")
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Invalid timezone.")
+			return
+		}
+	}
+
+	latitude, err := parseCoordinate(c.PostForm("latitude"), 90)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid latitude.")
+		return
+	}
+	longitude, err := parseCoordinate(c.PostForm("longitude"), 180)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid longitude.")
+		return
+	}
+	if (latitude == nil) != (longitude == nil) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Latitude and longitude must be provided together.")
+		return
+	}
+
+	resaleMaxMarkup := 0
+	if markupStr := c.PostForm("resale_max_markup"); markupStr != "" {
+		resaleMaxMarkup, err = helpers.StringToInt(markupStr)
+		if err != nil || resaleMaxMarkup < 0 {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Invalid resale max markup.")
+			return
+		}
+	}
 
 	var categories []string
 	for i := 0; ; i++ {
@@ -288,6 +539,7 @@ func UpdateEvent(c *gin.Context) {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Error finding event.")
 		return
 	}
+	previousVenueID := event.VenueID
 
 	event.Title = title
 	event.Description = description
@@ -298,13 +550,45 @@ func UpdateEvent(c *gin.Context) {
 	event.District = district
 	event.SubDistrict = subDistrict
 	event.Location = location
+	event.Latitude = latitude
+	event.Longitude = longitude
+	event.VenueID = nil
+	event.TransfersDisabled = transfersDisabled
+	event.ResaleEnabled = resaleEnabled
+	event.ResaleMaxMarkup = resaleMaxMarkup
+	if timezone != "" {
+		event.Timezone = timezone
+	}
+
+	venueChanged := false
+	if venueID != "" {
+		var venue models.Venue
+		if err := gormDB.Where("id = ?", venueID).First(&venue).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Venue not found.")
+			return
+		}
+		venueChanged = previousVenueID == nil || *previousVenueID != venue.ID
+		applyVenueLocation(&event, &venue)
+
+		if msg := venueCapacityError(gormDB, &event, nil, 0); msg != "" {
+			helpers.RespondWithError(c, http.StatusBadRequest, msg)
+			return
+		}
+
+		if venueChanged && eventSeatsLocked(gormDB, event.ID) {
+			helpers.RespondWithError(c, http.StatusConflict, "Seats have already been sold or held for this event.")
+			return
+		}
+	}
 
 	bannerFile, err := c.FormFile("banner")
 	if err == nil {
 		bannerPath, err := helpers.UploadFile(c, bannerFile, "event_banners")
 
-		if err := helpers.DeleteFile(event.BannerPath); err != nil {
-			fmt.Printf("Error deleting old banner: %v\n", err)
+		if event.BannerPath != "" && !bannerInUse(gormDB, event.BannerPath, event.ID) {
+			if err := helpers.DeleteFile(event.BannerPath); err != nil {
+				fmt.Printf("Error deleting old banner: %v\n", err)
+			}
 		}
 		if err != nil {
 			helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
@@ -323,7 +607,19 @@ func UpdateEvent(c *gin.Context) {
 		updatedCategories = append(updatedCategories, category)
 	}
 
-	if err := gormDB.Save(&event).Error; err != nil {
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&event).Error; err != nil {
+			return err
+		}
+		if !venueChanged {
+			return nil
+		}
+		if err := tx.Where("event_id = ?", event.ID).Delete(&models.SeatSection{}).Error; err != nil {
+			return err
+		}
+		return copyVenueSeatMap(tx, *event.VenueID, event.ID)
+	})
+	if err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update event.")
 		return
 	}
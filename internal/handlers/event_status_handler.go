@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// publicEventStatuses can be viewed by anyone; drafts and scheduled events
+// are only visible to their organizer.
+var publicEventStatuses = []string{models.EventStatusPublished, models.EventStatusCancelled, models.EventStatusCompleted}
+
+var listedEventStatuses = []string{models.EventStatusPublished, models.EventStatusCompleted}
+
+var eventStatuses = map[string]bool{
+	models.EventStatusDraft:     true,
+	models.EventStatusScheduled: true,
+	models.EventStatusPublished: true,
+	models.EventStatusCancelled: true,
+	models.EventStatusCompleted: true,
+}
+
+// parsePublishAt reads the optional publish_at form field. A missing or past
+// time means publish now.
+func parsePublishAt(c *gin.Context) (*time.Time, bool) {
+	value := c.PostForm("publish_at")
+	if value == "" {
+		return nil, true
+	}
+
+	publishAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid publish time format.")
+		return nil, false
+	}
+	if !publishAt.After(time.Now()) {
+		return nil, true
+	}
+	return &publishAt, true
+}
+
+// publishUpdates returns the column updates that publish an event now, or
+// schedule it when publishAt is set.
+func publishUpdates(publishAt *time.Time) map[string]interface{} {
+	if publishAt != nil {
+		return map[string]interface{}{
+			"status":     models.EventStatusScheduled,
+			"publish_at": *publishAt,
+		}
+	}
+	return map[string]interface{}{
+		"status":       models.EventStatusPublished,
+		"publish_at":   nil,
+		"published_at": time.Now(),
+	}
+}
+
+func PublishEvent(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	if event.Status != models.EventStatusDraft && event.Status != models.EventStatusScheduled {
+		helpers.RespondWithError(c, http.StatusConflict, "Only draft or scheduled events can be published.")
+		return
+	}
+
+	if !event.EndTime.After(time.Now()) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Event has already ended.")
+		return
+	}
+
+	var ticketCount int64
+	if err := gormDB.Model(&models.Ticket{}).Where("event_id = ?", event.ID).Count(&ticketCount).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving tickets.")
+		return
+	}
+	if ticketCount == 0 {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Add at least one ticket before publishing.")
+		return
+	}
+
+	publishAt, ok := parsePublishAt(c)
+	if !ok {
+		return
+	}
+	if publishAt != nil && !publishAt.Before(event.EndTime) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Publish time must be before the event ends.")
+		return
+	}
+
+	if err := gormDB.Model(event).Updates(publishUpdates(publishAt)).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to publish event.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Event status updated successfully.",
+		"status":       event.Status,
+		"publish_at":   event.PublishAt,
+		"published_at": event.PublishedAt,
+	})
+}
+
+func UnscheduleEvent(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	if event.Status != models.EventStatusScheduled {
+		helpers.RespondWithError(c, http.StatusConflict, "Only scheduled events can be moved back to draft.")
+		return
+	}
+
+	err := gormDB.Model(event).Updates(map[string]interface{}{
+		"status":     models.EventStatusDraft,
+		"publish_at": nil,
+	}).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to unschedule event.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Event moved back to draft.",
+		"status":  event.Status,
+	})
+}
+
+func CancelEvent(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	if msg := cancelEvent(gormDB, event); msg != "" {
+		helpers.RespondWithError(c, http.StatusConflict, msg)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Event cancelled successfully.",
+		"status":       event.Status,
+		"cancelled_at": event.CancelledAt,
+	})
+}
+
+// cancelEvent moves an event to cancelled. It returns a message when the
+// event is already cancelled or completed.
+func cancelEvent(gormDB *gorm.DB, event *models.Event) string {
+	switch event.Status {
+	case models.EventStatusCancelled:
+		return "Event is already cancelled."
+	case models.EventStatusCompleted:
+		return "Completed events cannot be cancelled."
+	}
+
+	err := gormDB.Model(event).Updates(map[string]interface{}{
+		"status":       models.EventStatusCancelled,
+		"cancelled_at": time.Now(),
+	}).Error
+	if err != nil {
+		return "Failed to cancel event."
+	}
+	return ""
+}
+
+func PreviewEvent(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var event models.Event
+	err := eventDetails(gormDB).Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&event).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusNotFound, "Event not found.")
+			return
+		}
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving event.")
+		return
+	}
+
+	if err := applyTicketSaleStatuses(gormDB, &event); err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving ticket availability.")
+		return
+	}
+	c.JSON(http.StatusOK, event)
+}
+
+func ListMyEvents(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	query := gormDB.Model(&models.Event{}).Where("events.user_id = ?", userID)
+	if statuses := helpers.NewQueryFilters(c).List("status"); len(statuses) > 0 {
+		for _, status := range statuses {
+			if !eventStatuses[status] {
+				helpers.RespondWithError(c, http.StatusBadRequest, "Invalid status.")
+				return
+			}
+		}
+		query = query.Where("events.status IN ?", statuses)
+	}
+
+	listEvents(c, gormDB, query)
+}
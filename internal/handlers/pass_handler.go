@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/middleware"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type passField struct {
+	Key       string `json:"key"`
+	Label     string `json:"label"`
+	Value     string `json:"value"`
+	DateStyle string `json:"dateStyle,omitempty"`
+	TimeStyle string `json:"timeStyle,omitempty"`
+}
+
+type passBarcode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+}
+
+// loadPassPurchase applies the same checks as GenerateTicketQR before a
+// purchase is exported to a phone wallet.
+func loadPassPurchase(c *gin.Context) (*models.Purchase, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User not authenticated.")
+		return nil, false
+	}
+
+	purchaseID, err := uuid.Parse(c.Param("purchaseId"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid purchase ID")
+		return nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found")
+		return nil, false
+	}
+	gormDB := db.(*gorm.DB)
+
+	var purchase models.Purchase
+	if err := gormDB.Preload("Ticket.Event").Preload("Payment").Preload("Attendee").Preload("Seat.Section").First(&purchase, purchaseID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found")
+		return nil, false
+	}
+
+	if purchase.UserID != userID {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to export this purchase")
+		return nil, false
+	}
+
+	if purchase.Payment.Status != "PAID" {
+		helpers.RespondWithError(c, http.StatusForbidden, "Payment is not paid")
+		return nil, false
+	}
+
+	if time.Now().After(purchase.Ticket.Event.EndTime) {
+		helpers.RespondWithError(c, http.StatusForbidden, "Ticket expired")
+		return nil, false
+	}
+
+	if purchase.IsUsed {
+		helpers.RespondWithError(c, http.StatusForbidden, "Ticket already used")
+		return nil, false
+	}
+
+	return &purchase, true
+}
+
+func GenerateApplePass(c *gin.Context) {
+	issuer := middleware.GetWalletIssuer(c)
+	if issuer == nil || issuer.AppleSigner == nil {
+		helpers.RespondWithError(c, http.StatusServiceUnavailable, "Apple Wallet passes are not configured")
+		return
+	}
+
+	purchase, ok := loadPassPurchase(c)
+	if !ok {
+		return
+	}
+	event := purchase.Ticket.Event
+	qrData := generateQRCodeData(purchase)
+
+	secondaryFields := []passField{
+		{Key: "ticket", Label: "TICKET", Value: purchase.Ticket.Type},
+	}
+	if purchase.Attendee != nil {
+		secondaryFields = append(secondaryFields, passField{Key: "attendee", Label: "ATTENDEE", Value: purchase.Attendee.Name})
+	}
+	if purchase.Seat != nil {
+		secondaryFields = append(secondaryFields, passField{Key: "seat", Label: "SEAT", Value: seatLabel(purchase.Seat.Section.Name, purchase.Seat)})
+	}
+
+	barcode := passBarcode{
+		Format:          "PKBarcodeFormatQR",
+		Message:         qrData,
+		MessageEncoding: "iso-8859-1",
+	}
+
+	pass := gin.H{
+		"formatVersion":      1,
+		"passTypeIdentifier": issuer.PassTypeIdentifier,
+		"teamIdentifier":     issuer.TeamIdentifier,
+		"organizationName":   issuer.OrganizationName,
+		"serialNumber":       purchase.ID.String(),
+		"description":        fmt.Sprintf("%s - %s", event.Title, purchase.Ticket.Type),
+		"relevantDate":       event.StartTime.Format(time.RFC3339),
+		"expirationDate":     event.EndTime.Format(time.RFC3339),
+		"barcode":            barcode,
+		"barcodes":           []passBarcode{barcode},
+		"eventTicket": gin.H{
+			"primaryFields": []passField{
+				{Key: "event", Label: "EVENT", Value: event.Title},
+			},
+			"secondaryFields": secondaryFields,
+			"auxiliaryFields": []passField{
+				{Key: "start", Label: "STARTS", Value: event.StartTime.Format(time.RFC3339), DateStyle: "PKDateStyleMedium", TimeStyle: "PKDateStyleShort"},
+				{Key: "location", Label: "LOCATION", Value: event.Location},
+			},
+			"backFields": []passField{
+				{Key: "address", Label: "ADDRESS", Value: fmt.Sprintf("%s, %s, %s, %s, %s", event.Location, event.SubDistrict, event.District, event.City, event.Province)},
+				{Key: "purchase", Label: "PURCHASE ID", Value: purchase.ID.String()},
+			},
+		},
+	}
+
+	passJSON, err := json.Marshal(pass)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to generate pass")
+		return
+	}
+
+	files := helpers.PassImages(event.BannerPath)
+	files["pass.json"] = passJSON
+
+	bundle, err := helpers.BuildPKPass(files, issuer.AppleSigner)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to sign pass")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.pkpass\"", purchase.ID))
+	c.Data(http.StatusOK, "application/vnd.apple.pkpass", bundle)
+}
+
+func GenerateGooglePassLink(c *gin.Context) {
+	issuer := middleware.GetWalletIssuer(c)
+	if issuer == nil || issuer.GoogleKey == nil {
+		helpers.RespondWithError(c, http.StatusServiceUnavailable, "Google Wallet passes are not configured")
+		return
+	}
+
+	purchase, ok := loadPassPurchase(c)
+	if !ok {
+		return
+	}
+	event := purchase.Ticket.Event
+
+	localized := func(value string) gin.H {
+		return gin.H{"defaultValue": gin.H{"language": "en-US", "value": value}}
+	}
+
+	classID := fmt.Sprintf("%s.%s", issuer.GoogleIssuerID, event.ID)
+	object := gin.H{
+		"id":      fmt.Sprintf("%s.%s", issuer.GoogleIssuerID, purchase.ID),
+		"classId": classID,
+		"state":   "ACTIVE",
+		"barcode": gin.H{
+			"type":  "QR_CODE",
+			"value": generateQRCodeData(purchase),
+		},
+		"ticketType": localized(purchase.Ticket.Type),
+		"validTimeInterval": gin.H{
+			"start": gin.H{"date": event.StartTime.Format(time.RFC3339)},
+			"end":   gin.H{"date": event.EndTime.Format(time.RFC3339)},
+		},
+	}
+	if purchase.Attendee != nil {
+		object["ticketHolderName"] = purchase.Attendee.Name
+	}
+	if purchase.Seat != nil {
+		object["seatInfo"] = gin.H{
+			"section": localized(purchase.Seat.Section.Name),
+			"row":     localized(purchase.Seat.Row),
+			"seat":    localized(fmt.Sprintf("%d", purchase.Seat.Number)),
+		}
+	}
+
+	saveURL, err := helpers.SignGoogleWalletJWT(issuer, map[string]interface{}{
+		"eventTicketClasses": []gin.H{
+			{
+				"id":           classID,
+				"issuerName":   issuer.OrganizationName,
+				"eventName":    localized(event.Title),
+				"reviewStatus": "UNDER_REVIEW",
+				"venue": gin.H{
+					"name":    localized(event.Location),
+					"address": localized(fmt.Sprintf("%s, %s, %s", event.District, event.City, event.Province)),
+				},
+				"dateTime": gin.H{
+					"start": event.StartTime.Format(time.RFC3339),
+					"end":   event.EndTime.Format(time.RFC3339),
+				},
+			},
+		},
+		"eventTicketObjects": []gin.H{object},
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to sign Google Wallet pass")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"save_url": saveURL,
+	})
+}
@@ -2,25 +2,178 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/farellandr/spoticket/internal/helpers"
 	"github.com/farellandr/spoticket/internal/middleware"
 	"github.com/farellandr/spoticket/internal/models"
+	"github.com/farellandr/spoticket/internal/pricing"
+	"github.com/farellandr/spoticket/internal/waitlist"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/xendit/xendit-go/v6"
 	"github.com/xendit/xendit-go/v6/invoice"
 	"github.com/xendit/xendit-go/v6/payout"
+	"github.com/xendit/xendit-go/v6/refund"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+type AttendeeRequest struct {
+	Name     string  `json:"name" binding:"required"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+	IDNumber *string `json:"id_number"`
+}
+
+type AddonRequest struct {
+	VariantID uuid.UUID `json:"variant_id" binding:"required"`
+	Quantity  int       `json:"quantity" binding:"required,min=1"`
+}
+
 type PaymentRequest struct {
-	TicketID uuid.UUID  `json:"ticket_id" binding:"required"`
-	CouponID *uuid.UUID `json:"coupon_id"`
-	Quantity int        `json:"quantity" binding:"required,min=1"`
+	TicketID       uuid.UUID         `json:"ticket_id" binding:"required"`
+	CouponID       *uuid.UUID        `json:"coupon_id"`
+	Quantity       int               `json:"quantity" binding:"required,min=1"`
+	Attendees      []AttendeeRequest `json:"attendees" binding:"dive"`
+	SeatIDs        []uuid.UUID       `json:"seat_ids"`
+	AdmissionToken string            `json:"admission_token"`
+	AccessCode     string            `json:"access_code"`
+	Addons         []AddonRequest    `json:"addons" binding:"dive"`
+}
+
+const checkoutHoldDuration = 15 * time.Minute
+
+// Error codes returned when a checkout breaks a ticket's purchase rules.
+const (
+	codeBelowMinPerOrder  = "below_min_per_order"
+	codeAboveMaxPerOrder  = "above_max_per_order"
+	codeMaxPerUserReached = "max_per_user_reached"
+	codePhoneNotVerified  = "phone_not_verified"
+	codePhoneAlreadyUsed  = "phone_already_used"
+)
+
+// activePaymentStatuses are payments that hold or have bought tickets.
+var activePaymentStatuses = []string{"PENDING", "PAID"}
+
+type checkoutError struct {
+	status  int
+	code    string
+	message string
+}
+
+// checkPurchaseRules enforces the ticket's per-order and per-user quantities
+// and phone verification requirements for a checkout of quantity tickets.
+// Resale checkouts move existing tickets, so they don't count against the
+// limits.
+func checkPurchaseRules(gormDB *gorm.DB, ticket *models.Ticket, user *models.User, quantity int) *checkoutError {
+	if ticket.MinPerOrder > 0 && quantity < ticket.MinPerOrder {
+		return &checkoutError{http.StatusBadRequest, codeBelowMinPerOrder, fmt.Sprintf("At least %d tickets must be bought per order.", ticket.MinPerOrder)}
+	}
+	if ticket.MaxPerOrder > 0 && quantity > ticket.MaxPerOrder {
+		return &checkoutError{http.StatusBadRequest, codeAboveMaxPerOrder, fmt.Sprintf("At most %d tickets can be bought per order.", ticket.MaxPerOrder)}
+	}
+
+	if (ticket.RequireVerifiedPhone || ticket.OnePerPhone) && user.PhoneVerifiedAt == nil {
+		return &checkoutError{http.StatusForbidden, codePhoneNotVerified, "Verify your phone number to buy this ticket."}
+	}
+
+	if ticket.OnePerPhone {
+		var shared int64
+		err := gormDB.Model(&models.Payment{}).Joins("JOIN users ON users.id = payments.user_id").
+			Where("payments.ticket_id = ? AND payments.status IN ? AND payments.transaction_id NOT LIKE ? AND users.phone_number = ? AND users.id <> ?", ticket.ID, activePaymentStatuses, "RSL-%", user.PhoneNumber, user.ID).
+			Count(&shared).Error
+		if err != nil {
+			return &checkoutError{status: http.StatusInternalServerError, message: "Error checking purchase limits."}
+		}
+		if shared > 0 {
+			return &checkoutError{http.StatusConflict, codePhoneAlreadyUsed, "Another account with this phone number has already bought this ticket."}
+		}
+	}
+
+	if ticket.MaxPerUser > 0 {
+		var bought int64
+		err := gormDB.Model(&models.Payment{}).Select("COALESCE(SUM(quantity), 0)").
+			Where("ticket_id = ? AND user_id = ? AND status IN ? AND transaction_id NOT LIKE ?", ticket.ID, user.ID, activePaymentStatuses, "RSL-%").
+			Scan(&bought).Error
+		if err != nil {
+			return &checkoutError{status: http.StatusInternalServerError, message: "Error checking purchase limits."}
+		}
+		if int(bought)+quantity > ticket.MaxPerUser {
+			remaining := max(ticket.MaxPerUser-int(bought), 0)
+			return &checkoutError{http.StatusBadRequest, codeMaxPerUserReached, fmt.Sprintf("You can buy at most %d of this ticket; %d remaining.", ticket.MaxPerUser, remaining)}
+		}
+	}
+
+	return nil
+}
+
+// errCheckoutRejected aborts a checkout transaction after it set a
+// checkoutError for the response.
+var errCheckoutRejected = errors.New("checkout rejected")
+
+// errPaymentExpired aborts issuing tickets for a payment whose invoice expired
+// before the PAID callback arrived.
+var errPaymentExpired = errors.New("payment already expired")
+
+// refundPayment returns the money of a paid invoice that can no longer be
+// fulfilled and marks its payment refunded. The reference is also the
+// idempotency key, so a retried callback doesn't refund twice.
+func refundPayment(gormDB *gorm.DB, xenditClient *xendit.APIClient, payload *invoice.InvoiceCallback, payment *models.Payment, reference string) error {
+	amount := payload.Amount
+	reason := "CANCELLATION"
+	refundRequest := refund.CreateRefund{
+		InvoiceId:   &payload.Id,
+		ReferenceId: &reference,
+		Amount:      &amount,
+		Reason:      &reason,
+	}
+
+	_, _, xndErr := xenditClient.RefundApi.CreateRefund(context.Background()).
+		IdempotencyKey(reference).
+		CreateRefund(refundRequest).
+		Execute()
+	if xndErr != nil {
+		return xndErr
+	}
+
+	return gormDB.Model(payment).Updates(map[string]interface{}{
+		"amount": int(payload.Amount),
+		"method": *payload.PaymentMethod,
+		"status": "REFUNDED",
+	}).Error
+}
+
+// abandonCheckout gives back what a pending payment reserved when its invoice
+// couldn't be created.
+func abandonCheckout(gormDB *gorm.DB, payment *models.Payment, offer *models.WaitlistEntry, admission *models.QueueEntry) error {
+	result := gormDB.Model(&models.Payment{}).Where("id = ? AND status = ?", payment.ID, "PENDING").Update("status", "EXPIRED")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	if err := releaseSeats(gormDB, payment.TransactionID); err != nil {
+		return err
+	}
+	if admission != nil {
+		if err := restoreAdmission(gormDB, admission); err != nil {
+			return err
+		}
+	}
+	if offer != nil {
+		return gormDB.Model(offer).Updates(map[string]interface{}{
+			"status":     models.WaitlistStatusOffered,
+			"payment_id": nil,
+		}).Error
+	}
+	return nil
 }
 
 func CreatePaymentLink(c *gin.Context) {
@@ -65,13 +218,72 @@ func CreatePaymentLink(c *gin.Context) {
 		return
 	}
 
-	if time.Now().After(ticket.Event.EndTime) {
-		helpers.RespondWithError(c, http.StatusForbidden, "Ticket expired")
+	if ticket.Event.Status == models.EventStatusCancelled {
+		helpers.RespondWithError(c, http.StatusForbidden, "Event has been cancelled.")
+		return
+	}
+	if ticket.Event.Status != models.EventStatusPublished {
+		helpers.RespondWithError(c, http.StatusForbidden, "Event is not on sale.")
 		return
 	}
 
-	if len(ticket.Purchases) > ticket.Limit || paymentReq.Quantity+len(ticket.Purchases) > ticket.Limit {
-		helpers.RespondWithError(c, http.StatusBadRequest, "Ticket limit exceeded.")
+	if checkoutErr := checkAdmission(gormDB, ticket.EventID, userUUID, paymentReq.AdmissionToken); checkoutErr != nil {
+		helpers.RespondWithErrorCode(c, checkoutErr.status, checkoutErr.code, checkoutErr.message)
+		return
+	}
+
+	var tiers []models.Ticket
+	if err := gormDB.Preload("Purchases").Where("event_id = ?", ticket.EventID).Find(&tiers).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving ticket tiers.")
+		return
+	}
+	if err := loadPendingQuantities(gormDB, tiers); err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving ticket tiers.")
+		return
+	}
+	tiersByID := make(map[uuid.UUID]*models.Ticket, len(tiers))
+	for i := range tiers {
+		tiersByID[tiers[i].ID] = &tiers[i]
+	}
+	if tier, ok := tiersByID[ticket.ID]; ok {
+		ticket.PendingQuantity = tier.PendingQuantity
+	}
+
+	switch ticketSaleStatus(&ticket, ticket.Event, tiersByID, time.Now()) {
+	case models.TicketSaleUpcoming:
+		helpers.RespondWithError(c, http.StatusForbidden, "Ticket sales have not started yet.")
+		return
+	case models.TicketSaleEnded:
+		helpers.RespondWithError(c, http.StatusForbidden, "Ticket sales have ended.")
+		return
+	}
+
+	accessCodeID, checkoutErr := checkTicketAccess(gormDB, &ticket, userUUID, paymentReq.AccessCode)
+	if checkoutErr != nil {
+		helpers.RespondWithErrorCode(c, checkoutErr.status, checkoutErr.code, checkoutErr.message)
+		return
+	}
+
+	if len(paymentReq.Attendees) > paymentReq.Quantity {
+		helpers.RespondWithError(c, http.StatusBadRequest, "More attendees than tickets were provided.")
+		return
+	}
+	if ticket.RequireAttendeeDetails && len(paymentReq.Attendees) != paymentReq.Quantity {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Attendee details are required for every ticket.")
+		return
+	}
+
+	var mappedSeats int64
+	if err := gormDB.Model(&models.Seat{}).Where("ticket_id = ?", ticket.ID).Count(&mappedSeats).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving seats.")
+		return
+	}
+	if mappedSeats == 0 && len(paymentReq.SeatIDs) > 0 {
+		helpers.RespondWithError(c, http.StatusBadRequest, "This ticket does not have assigned seating.")
+		return
+	}
+	if mappedSeats > 0 && len(paymentReq.SeatIDs) != paymentReq.Quantity {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Select one seat for every ticket.")
 		return
 	}
 
@@ -81,23 +293,46 @@ func CreatePaymentLink(c *gin.Context) {
 		return
 	}
 
+	if checkoutErr := checkPurchaseRules(gormDB, &ticket, &user, paymentReq.Quantity); checkoutErr != nil {
+		helpers.RespondWithErrorCode(c, checkoutErr.status, checkoutErr.code, checkoutErr.message)
+		return
+	}
+
+	addonItems, checkoutErr := prepareAddons(gormDB, ticket.EventID, paymentReq.Addons)
+	if checkoutErr != nil {
+		helpers.RespondWithErrorCode(c, checkoutErr.status, checkoutErr.code, checkoutErr.message)
+		return
+	}
+
 	var categoryNames []string
 	for _, category := range ticket.Event.Categories {
 		categoryNames = append(categoryNames, category.Name)
 	}
 	categoriesStr := strings.Join(categoryNames, ",")
 
-	totalAmount := int(ticket.Price * paymentReq.Quantity)
-	var appliedCoupon int
+	quote, err := quoteTicket(gormDB, &ticket, paymentReq.Quantity)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error calculating price.")
+		return
+	}
+
+	totalAmount := quote.Total
+	var couponDiscount int
+	var couponLabel string
 	var usedCouponID *uuid.UUID
+	var coupon models.Coupon
 
 	if paymentReq.CouponID != nil {
-		var coupon models.Coupon
-		if err := gormDB.First(&coupon, paymentReq.CouponID).Error; err != nil {
+		if err := gormDB.Preload("Events").Preload("Categories").Preload("Tickets").First(&coupon, paymentReq.CouponID).Error; err != nil {
 			helpers.RespondWithError(c, http.StatusNotFound, "Coupon not found.")
 			return
 		}
 
+		if !couponApplies(&coupon, &ticket) {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Coupon does not apply to this ticket.")
+			return
+		}
+
 		now := time.Now()
 		if now.Before(coupon.ValidAt) || now.After(coupon.ExpiredAt) {
 			helpers.RespondWithError(c, http.StatusBadRequest, "Coupon is not currently valid.")
@@ -111,53 +346,213 @@ func CreatePaymentLink(c *gin.Context) {
 			return
 		}
 
-		if userCoupon.IsUsed {
+		if userCoupon.UsedCount >= coupon.MaxUsesPerUser {
 			helpers.RespondWithError(c, http.StatusBadRequest, "Coupon has already been used.")
 			return
 		}
 
-		appliedCoupon = int(coupon.Discount)
-		totalAmount = totalAmount * (100 - appliedCoupon) / 100
+		discount, err := pricing.CouponDiscount(&coupon, quote)
+		switch err {
+		case nil:
+		case pricing.ErrBelowMinQuantity:
+			helpers.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("Coupon requires at least %d tickets.", coupon.MinQuantity))
+			return
+		case pricing.ErrBelowMinOrder:
+			helpers.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("Coupon requires a minimum order of %d.", coupon.MinOrderAmount))
+			return
+		case pricing.ErrNotStackable:
+			helpers.RespondWithError(c, http.StatusBadRequest, "Coupon can't be combined with other discounts.")
+			return
+		}
+
+		couponDiscount = discount
+		couponLabel = pricing.CouponLabel(&coupon)
+		totalAmount -= couponDiscount
 		usedCouponID = &coupon.ID
 	}
 
+	// Discounts are taken off the ticket line rather than listed as fees, so
+	// the invoice's fees are only what the platform keeps.
+	ticketItem := invoice.InvoiceItem{
+		Name:     fmt.Sprintf("%s - %s", ticket.Event.Title, ticket.Type),
+		Quantity: float32(paymentReq.Quantity),
+		Price:    float32(totalAmount / paymentReq.Quantity),
+		Category: &categoriesStr,
+	}
+	if totalAmount%paymentReq.Quantity != 0 {
+		ticketItem.Name = fmt.Sprintf("%s - %s (Qty: %d)", ticket.Event.Title, ticket.Type, paymentReq.Quantity)
+		ticketItem.Quantity = 1
+		ticketItem.Price = float32(totalAmount)
+	}
+	items := []invoice.InvoiceItem{ticketItem}
+
+	var discounts []string
+	for _, rule := range quote.Applied {
+		discounts = append(discounts, fmt.Sprintf("%s %d", rule.Name, rule.Amount))
+	}
+	if couponDiscount > 0 {
+		discounts = append(discounts, fmt.Sprintf("%s %d", couponLabel, -couponDiscount))
+	}
+
+	var orderItems []models.AddonOrderItem
+	for _, item := range addonItems {
+		category := item.ProductVariant.Product.Type
+		items = append(items, invoice.InvoiceItem{
+			Name:     fmt.Sprintf("%s - %s", ticket.Event.Title, addonName(item.ProductVariant)),
+			Quantity: float32(item.Quantity),
+			Price:    float32(item.Price),
+			Category: &category,
+		})
+		totalAmount += item.Price * item.Quantity
+		orderItems = append(orderItems, models.AddonOrderItem{
+			ProductVariantID: item.ProductVariantID,
+			Quantity:         item.Quantity,
+			Price:            item.Price,
+		})
+	}
+
 	adminFeePercent := 1.5
 	adminFee := int(float64(totalAmount) * float64(adminFeePercent) / 100)
+	fees := []invoice.InvoiceFee{
+		{
+			Type:  fmt.Sprintf("Admin Fee (%.1f%%)", adminFeePercent),
+			Value: float32(adminFee),
+		},
+	}
 
 	descStr := fmt.Sprintf("%s - %s (Qty: %d)",
 		ticket.Event.Title,
 		ticket.Type,
 		paymentReq.Quantity,
 	)
+	if len(discounts) > 0 {
+		descStr += fmt.Sprintf(" [%s]", strings.Join(discounts, ", "))
+	}
+
+	externalID := fmt.Sprintf("INV-%d-%s", time.Now().Unix(), helpers.EncryptExternalID(ticket.ID, usedCouponID))
+
+	var invoiceDuration *string
+	if len(paymentReq.SeatIDs) > 0 {
+		duration := strconv.Itoa(int(checkoutHoldDuration.Seconds()))
+		invoiceDuration = &duration
+	}
+
+	payment := models.Payment{
+		Amount:        totalAmount + adminFee,
+		AdminFee:      adminFee,
+		Status:        "PENDING",
+		TransactionID: externalID,
+		UserID:        user.ID,
+		TicketID:      &ticket.ID,
+		Quantity:      paymentReq.Quantity,
+		CouponID:      usedCouponID,
+		AccessCodeID:  accessCodeID,
+		AddonItems:    orderItems,
+		PricingRules:  quote.Applied,
+	}
+	for i, attendee := range paymentReq.Attendees {
+		payment.Attendees = append(payment.Attendees, models.Attendee{
+			Position: i,
+			Name:     attendee.Name,
+			Email:    attendee.Email,
+			IDNumber: attendee.IDNumber,
+		})
+	}
+
+	// The ticket row stays locked from the availability check until the
+	// pending payment is recorded, so concurrent checkouts can't both take the
+	// last tickets. The invoice is only created once the tickets are reserved.
+	var offer *models.WaitlistEntry
+	var admission *models.QueueEntry
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Ticket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, ticket.ID).Error; err != nil {
+			return err
+		}
+
+		available, openOffer, err := waitlist.Available(tx, &locked, userUUID)
+		if err != nil {
+			return err
+		}
+		if paymentReq.Quantity > available {
+			checkoutErr = &checkoutError{http.StatusBadRequest, codeSoldOut, "Not enough tickets available. Join the waitlist to get an offer when tickets free up."}
+			return errCheckoutRejected
+		}
+
+		if checkoutErr = reserveAddons(tx, addonItems); checkoutErr != nil {
+			return errCheckoutRejected
+		}
+
+		if usedCouponID != nil {
+			switch err := reserveCoupon(tx, user.ID, *usedCouponID); err {
+			case nil:
+			case errCouponUsedUp, errCouponRedemptionsEnded:
+				checkoutErr = &checkoutError{status: http.StatusBadRequest, message: "Coupon has already been used."}
+				return errCheckoutRejected
+			default:
+				return err
+			}
+		}
+
+		// Seats are held in the same transaction, so a failed checkout never
+		// leaves them held without a payment to release them.
+		if len(paymentReq.SeatIDs) > 0 {
+			held, err := holdSeats(tx, ticket.ID, user.ID, paymentReq.SeatIDs, externalID, time.Now().Add(checkoutHoldDuration))
+			if err != nil {
+				return err
+			}
+			if !held {
+				checkoutErr = &checkoutError{status: http.StatusConflict, message: "One or more selected seats are no longer available."}
+				return errCheckoutRejected
+			}
+		}
+
+		if admission, checkoutErr = consumeAdmission(tx, ticket.EventID, userUUID); checkoutErr != nil {
+			return errCheckoutRejected
+		}
+
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+
+		if openOffer != nil {
+			offer = openOffer
+			return tx.Model(offer).Updates(map[string]interface{}{
+				"status":     models.WaitlistStatusConverted,
+				"payment_id": payment.ID,
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if checkoutErr != nil {
+			helpers.RespondWithErrorCode(c, checkoutErr.status, checkoutErr.code, checkoutErr.message)
+			return
+		}
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to record payment.")
+		return
+	}
 
 	invoiceRequest := invoice.CreateInvoiceRequest{
-		ExternalId:  fmt.Sprintf("INV-%d-%s", time.Now().Unix(), helpers.EncryptExternalID(ticket.ID, usedCouponID)),
-		Amount:      float64(totalAmount + adminFee),
-		PayerEmail:  &user.Email,
-		Description: &descStr,
+		ExternalId:      externalID,
+		Amount:          float64(totalAmount + adminFee),
+		PayerEmail:      &user.Email,
+		Description:     &descStr,
+		InvoiceDuration: invoiceDuration,
 		Customer: &invoice.CustomerObject{
 			GivenNames:   *invoice.NewNullableString(&user.Name),
 			Email:        *invoice.NewNullableString(&user.Email),
 			MobileNumber: *invoice.NewNullableString(&user.PhoneNumber),
 		},
-		Fees: []invoice.InvoiceFee{
-			{
-				Type:  fmt.Sprintf("Admin Fee (%.1f%%)", adminFeePercent),
-				Value: float32(adminFee),
-			},
-		},
-		Items: []invoice.InvoiceItem{
-			{
-				Name:     fmt.Sprintf("%s - %s", ticket.Event.Title, ticket.Type),
-				Quantity: float32(paymentReq.Quantity),
-				Price:    float32(totalAmount / paymentReq.Quantity),
-				Category: &categoriesStr,
-			},
-		},
+		Fees:  fees,
+		Items: items,
 	}
 
-	resp, _, err := xenditClient.InvoiceApi.CreateInvoice(context.Background()).CreateInvoiceRequest(invoiceRequest).Execute()
-	if err != nil {
+	resp, _, xndErr := xenditClient.InvoiceApi.CreateInvoice(context.Background()).CreateInvoiceRequest(invoiceRequest).Execute()
+	if xndErr != nil {
+		if err := abandonCheckout(gormDB, &payment, offer, admission); err != nil {
+			fmt.Printf("Error abandoning checkout %s: %v\n", externalID, err)
+		}
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create payment link.")
 		return
 	}
@@ -187,10 +582,43 @@ func PaymentNotification(c *gin.Context) {
 		return
 	}
 
+	if payload.Status == "EXPIRED" {
+		var expired models.Payment
+		if err := gormDB.Where("transaction_id = ?", payload.ExternalId).First(&expired).Error; err != nil && err != gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to find payment.")
+			return
+		}
+		result := gormDB.Model(&models.Payment{}).Where("transaction_id = ? AND status = ?", payload.ExternalId, "PENDING").Update("status", "EXPIRED")
+		if result.Error != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update payment.")
+			return
+		}
+		if err := releaseSeats(gormDB, payload.ExternalId); err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to release seats.")
+			return
+		}
+		if expired.TicketID != nil {
+			if err := waitlist.OfferFreedTickets(gormDB, *expired.TicketID); err != nil {
+				fmt.Printf("Error offering waitlisted tickets: %v\n", err)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Payment expired",
+		})
+		return
+	}
+
 	if payload.Status != "PAID" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "Payment is not paid",
 		})
+		return
+	}
+
+	if payload.Status == "PAID" && strings.HasPrefix(payload.ExternalId, "RSL-") {
+		handleResalePayment(c, gormDB, xenditClient, payload)
+		return
 	}
 
 	if payload.Status == "PAID" {
@@ -206,41 +634,151 @@ func PaymentNotification(c *gin.Context) {
 			return
 		}
 
-		payment := models.Payment{
-			Amount:        int(payload.Amount),
-			Method:        *payload.PaymentMethod,
-			Status:        payload.Status,
-			UserID:        user.ID,
-			CouponID:      couponID,
-			TransactionID: payload.ExternalId,
+		var payment models.Payment
+		err := gormDB.Where("transaction_id = ?", payload.ExternalId).First(&payment).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving payment.")
+			return
 		}
 
-		if err := gormDB.Create(&payment).Error; err != nil {
-			helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create payment.")
-			return
+		if err == gorm.ErrRecordNotFound {
+			payment = models.Payment{
+				Amount:        int(payload.Amount),
+				Status:        "PENDING",
+				TransactionID: payload.ExternalId,
+				UserID:        user.ID,
+				TicketID:      &ticketID,
+				CouponID:      couponID,
+			}
+			if err := gormDB.Create(&payment).Error; err != nil {
+				helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create payment.")
+				return
+			}
 		}
 
-		for _, item := range payload.Items {
-			for i := 0; i < int(item.Quantity); i++ {
+		// Only the callback that moves the payment from PENDING to PAID issues
+		// tickets, so retried or concurrent callbacks can't issue them twice.
+		// A payment that expired first already gave its seats and tickets
+		// back, so it is refunded instead.
+		failure := "Failed to process payment."
+		err = gormDB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Payment{}).Where("id = ? AND status = ?", payment.ID, "PENDING").Updates(map[string]interface{}{
+				"amount":  int(payload.Amount),
+				"method":  *payload.PaymentMethod,
+				"status":  "PAID",
+				"user_id": user.ID,
+			})
+			if result.Error != nil {
+				failure = "Failed to update payment."
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				var current models.Payment
+				if err := tx.Select("status").First(&current, payment.ID).Error; err != nil {
+					return err
+				}
+				if current.Status == "EXPIRED" {
+					return errPaymentExpired
+				}
+				return errPaymentProcessed
+			}
+			payment.UserID = user.ID
+
+			if payment.CouponID != nil {
+				if err := redeemCoupon(tx, payment.UserID, *payment.CouponID); err != nil {
+					failure = "Failed to redeem coupon."
+					return err
+				}
+			}
+
+			quantity := payment.Quantity
+			if quantity == 0 {
+				for _, item := range payload.Items {
+					quantity += int(item.Quantity)
+				}
+			}
+
+			var attendees []models.Attendee
+			tx.Where("payment_id = ? AND purchase_id IS NULL", payment.ID).Order("position").Find(&attendees)
+
+			var seats []models.Seat
+			tx.Where("hold_reference = ? AND purchase_id IS NULL", payload.ExternalId).Order("row, number").Find(&seats)
+
+			for i := 0; i < quantity; i++ {
 				purchase := models.Purchase{
 					TicketID:  ticketID,
 					UserID:    payment.UserID,
 					PaymentID: payment.ID,
 					IsUsed:    false,
 				}
+				if i < len(seats) {
+					purchase.SeatID = &seats[i].ID
+				}
+
+				if err := tx.Create(&purchase).Error; err != nil {
+					failure = "Failed to create purchase."
+					return err
+				}
+
+				if i < len(seats) {
+					err := tx.Model(&seats[i]).Updates(map[string]interface{}{
+						"purchase_id":     purchase.ID,
+						"held_by_user_id": nil,
+						"held_until":      nil,
+						"hold_reference":  nil,
+					}).Error
+					if err != nil {
+						failure = "Failed to assign seat."
+						return err
+					}
+				}
 
-				if err := gormDB.Create(&purchase).Error; err != nil {
-					helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create purchase.")
-					return
+				if i < len(attendees) {
+					if err := tx.Model(&attendees[i]).Update("purchase_id", purchase.ID).Error; err != nil {
+						failure = "Failed to assign attendee."
+						return err
+					}
 				}
 			}
-		}
 
-		if couponID != nil {
-			if err := gormDB.Model(&models.UserCoupon{}).Where("user_id = ? AND coupon_id = ?", user.ID, *couponID).Update("is_used", true).Error; err != nil {
-				helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update user coupon.")
+			var addonItems []models.AddonOrderItem
+			tx.Where("payment_id = ?", payment.ID).Find(&addonItems)
+
+			for _, item := range addonItems {
+				for i := 0; i < item.Quantity; i++ {
+					voucher := models.AddonVoucher{
+						PaymentID:        payment.ID,
+						UserID:           payment.UserID,
+						ProductVariantID: item.ProductVariantID,
+					}
+					if err := tx.Create(&voucher).Error; err != nil {
+						failure = "Failed to create add-on voucher."
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errPaymentProcessed) {
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Payment already processed",
+			})
+			return
+		}
+		if errors.Is(err, errPaymentExpired) {
+			if err := refundPayment(gormDB, xenditClient, payload, &payment, fmt.Sprintf("late-refund-%s", payment.ID)); err != nil {
+				fmt.Printf("Error refunding late payment %s: %v\n", payment.TransactionID, err)
+				helpers.RespondWithError(c, http.StatusInternalServerError, "Payment arrived after expiry and the refund failed.")
 				return
 			}
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Payment arrived after expiry. Payment refunded.",
+			})
+			return
+		}
+		if err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, failure)
+			return
 		}
 
 		var Ticket models.Ticket
@@ -249,9 +787,16 @@ func PaymentNotification(c *gin.Context) {
 			return
 		}
 
-		totalFee := float64(0)
-		for _, fee := range payload.Fees {
-			totalFee += float64(fee.Value)
+		// The organizer gets what the buyer paid less the platform's admin
+		// fee. Payments from before the fee was recorded fall back to the
+		// invoice's positive fees.
+		totalFee := float64(payment.AdminFee)
+		if payment.AdminFee == 0 {
+			for _, fee := range payload.Fees {
+				if fee.Value > 0 {
+					totalFee += float64(fee.Value)
+				}
+			}
 		}
 
 		idempotencyKey := fmt.Sprintf("disb-%s", uuid.New().String())
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/middleware"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	phoneCodeTTL         = 10 * time.Minute
+	phoneCodeCooldown    = time.Minute
+	maxPhoneCodeAttempts = 5
+	phoneCodeLength      = 6
+)
+
+type ConfirmPhoneVerificationRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func generatePhoneCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < phoneCodeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", phoneCodeLength, n), nil
+}
+
+func RequestPhoneVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	sender := middleware.GetSMSSender(c)
+	if sender == nil {
+		helpers.RespondWithError(c, http.StatusServiceUnavailable, "Phone verification is not available.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var user models.User
+	if err := gormDB.Where("id = ?", userID).First(&user).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	if user.PhoneVerifiedAt != nil {
+		helpers.RespondWithError(c, http.StatusConflict, "Phone number is already verified.")
+		return
+	}
+
+	var recent int64
+	gormDB.Model(&models.PhoneVerification{}).Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-phoneCodeCooldown)).Count(&recent)
+	if recent > 0 {
+		helpers.RespondWithError(c, http.StatusTooManyRequests, "Please wait before requesting another code.")
+		return
+	}
+
+	code, err := generatePhoneCode()
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to generate verification code.")
+		return
+	}
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to generate verification code.")
+		return
+	}
+
+	verification := models.PhoneVerification{
+		UserID:      user.ID,
+		PhoneNumber: user.PhoneNumber,
+		CodeHash:    string(codeHash),
+		ExpiresAt:   time.Now().Add(phoneCodeTTL),
+	}
+	if err := gormDB.Create(&verification).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create verification.")
+		return
+	}
+
+	message := fmt.Sprintf("Your Spoticket verification code is %s. It expires in %d minutes.", code, int(phoneCodeTTL.Minutes()))
+	if err := sender.Send(user.PhoneNumber, message); err != nil {
+		gormDB.Delete(&verification)
+		helpers.RespondWithError(c, http.StatusBadGateway, "Failed to send verification code.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Verification code sent.",
+		"expires_at": verification.ExpiresAt,
+	})
+}
+
+func ConfirmPhoneVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	var req ConfirmPhoneVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var user models.User
+	if err := gormDB.Where("id = ?", userID).First(&user).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	var verification models.PhoneVerification
+	err := gormDB.Where("user_id = ? AND verified_at IS NULL", user.ID).Order("created_at DESC").First(&verification).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "No pending verification. Request a new code.")
+		return
+	}
+
+	if time.Now().After(verification.ExpiresAt) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Verification code has expired.")
+		return
+	}
+	if verification.PhoneNumber != user.PhoneNumber {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Phone number has changed since the code was sent.")
+		return
+	}
+	if verification.Attempts >= maxPhoneCodeAttempts {
+		helpers.RespondWithError(c, http.StatusTooManyRequests, "Too many attempts. Request a new code.")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(verification.CodeHash), []byte(req.Code)); err != nil {
+		gormDB.Model(&verification).Update("attempts", gorm.Expr("attempts + 1"))
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid verification code.")
+		return
+	}
+
+	now := time.Now()
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&verification).Update("verified_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("phone_verified_at", now).Error
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to verify phone number.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Phone number verified successfully.",
+		"phone_verified_at": now,
+	})
+}
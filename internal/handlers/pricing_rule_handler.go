@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/farellandr/spoticket/internal/pricing"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PricingRuleRequest struct {
+	Type          string `json:"type" binding:"required"`
+	Name          string `json:"name" binding:"required"`
+	IsActive      *bool  `json:"is_active"`
+	MinQuantity   int    `json:"min_quantity"`
+	PercentOff    int    `json:"percent_off"`
+	BuyQuantity   int    `json:"buy_quantity"`
+	FreeQuantity  int    `json:"free_quantity"`
+	SoldThreshold int    `json:"sold_threshold"`
+	Price         int    `json:"price"`
+}
+
+func validatePricingRule(req *PricingRuleRequest) string {
+	switch req.Type {
+	case models.PricingRuleGroupDiscount:
+		if req.MinQuantity < 2 {
+			return "Minimum quantity must be at least 2."
+		}
+		if req.PercentOff < 1 || req.PercentOff > 100 {
+			return "Percent off must be between 1 and 100."
+		}
+	case models.PricingRuleBuyXGetY:
+		if req.BuyQuantity < 1 || req.FreeQuantity < 1 {
+			return "Buy and free quantities must be at least 1."
+		}
+	case models.PricingRuleDemandStep:
+		if req.SoldThreshold < 1 {
+			return "Sold threshold must be at least 1."
+		}
+		if req.Price < 0 {
+			return "Price must not be negative."
+		}
+	default:
+		return "Type must be group_discount, buy_x_get_y or demand_step."
+	}
+	return ""
+}
+
+func applyPricingRuleRequest(rule *models.PricingRule, req *PricingRuleRequest) {
+	rule.Type = req.Type
+	rule.Name = req.Name
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	rule.MinQuantity = req.MinQuantity
+	rule.PercentOff = req.PercentOff
+	rule.BuyQuantity = req.BuyQuantity
+	rule.FreeQuantity = req.FreeQuantity
+	rule.SoldThreshold = req.SoldThreshold
+	rule.Price = req.Price
+}
+
+func findOrganizerTicket(c *gin.Context, gormDB *gorm.DB) (*models.Ticket, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return nil, false
+	}
+
+	var ticket models.Ticket
+	err := gormDB.Joins("JOIN events ON events.id = tickets.event_id").
+		Where("tickets.id = ? AND events.user_id = ?", c.Param("id"), userID).
+		First(&ticket).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusForbidden, "Ticket not found or you don't have permission to modify it.")
+			return nil, false
+		}
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving ticket.")
+		return nil, false
+	}
+
+	return &ticket, true
+}
+
+func CreatePricingRule(c *gin.Context) {
+	var req PricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+	if msg := validatePricingRule(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	ticket, ok := findOrganizerTicket(c, gormDB)
+	if !ok {
+		return
+	}
+
+	rule := models.PricingRule{TicketID: ticket.ID, IsActive: true}
+	applyPricingRuleRequest(&rule, &req)
+
+	if err := gormDB.Create(&rule).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create pricing rule.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":         "Pricing rule created successfully.",
+		"pricing_rule_id": rule.ID,
+	})
+}
+
+func ListPricingRules(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	ticket, ok := findOrganizerTicket(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var rules []models.PricingRule
+	if err := gormDB.Where("ticket_id = ?", ticket.ID).Order("created_at").Find(&rules).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving pricing rules.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pricing_rules": rules,
+	})
+}
+
+func UpdatePricingRule(c *gin.Context) {
+	var req PricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+	if msg := validatePricingRule(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	ticket, ok := findOrganizerTicket(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var rule models.PricingRule
+	if err := gormDB.Where("id = ? AND ticket_id = ?", c.Param("ruleId"), ticket.ID).First(&rule).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Pricing rule not found.")
+		return
+	}
+
+	applyPricingRuleRequest(&rule, &req)
+	if err := gormDB.Save(&rule).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update pricing rule.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pricing rule updated successfully.",
+	})
+}
+
+func DeletePricingRule(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	ticket, ok := findOrganizerTicket(c, gormDB)
+	if !ok {
+		return
+	}
+
+	result := gormDB.Where("id = ? AND ticket_id = ?", c.Param("ruleId"), ticket.ID).Delete(&models.PricingRule{})
+	if result.Error != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to delete pricing rule.")
+		return
+	}
+	if result.RowsAffected == 0 {
+		helpers.RespondWithError(c, http.StatusNotFound, "Pricing rule not found.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pricing rule deleted successfully.",
+	})
+}
+
+// QuoteTicket prices an order of the ticket with its current pricing rules,
+// before coupons and fees.
+func QuoteTicket(c *gin.Context) {
+	quantity, err := strconv.Atoi(c.DefaultQuery("quantity", "1"))
+	if err != nil || quantity < 1 {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Quantity must be at least 1.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var ticket models.Ticket
+	if err := gormDB.Where("id = ?", c.Param("id")).First(&ticket).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Ticket not found.")
+		return
+	}
+
+	if ticket.Visibility == models.TicketVisibilityHidden && !unlockedTicketIDs(gormDB, ticket.EventID, c.Query("access_code"))[ticket.ID] {
+		helpers.RespondWithError(c, http.StatusNotFound, "Ticket not found.")
+		return
+	}
+
+	quote, err := quoteTicket(gormDB, &ticket, quantity)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error calculating price.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"unit_price":    quote.UnitPrice,
+		"quantity":      quote.Quantity,
+		"subtotal":      quote.Subtotal,
+		"applied_rules": quote.Applied,
+		"total":         quote.Total,
+	})
+}
+
+func quoteTicket(gormDB *gorm.DB, ticket *models.Ticket, quantity int) (pricing.Quote, error) {
+	var rules []models.PricingRule
+	if err := gormDB.Where("ticket_id = ? AND is_active = ?", ticket.ID, true).Find(&rules).Error; err != nil {
+		return pricing.Quote{}, err
+	}
+
+	var sold int64
+	if err := gormDB.Model(&models.Purchase{}).Where("ticket_id = ?", ticket.ID).Count(&sold).Error; err != nil {
+		return pricing.Quote{}, err
+	}
+
+	return pricing.Evaluate(ticket.Price, quantity, int(sold), rules), nil
+}
@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const codeAddonSoldOut = "addon_sold_out"
+
+var productTypes = map[string]bool{
+	models.ProductTypeParking:     true,
+	models.ProductTypeMerchandise: true,
+	models.ProductTypeFood:        true,
+}
+
+type ProductVariantRequest struct {
+	ID    *uuid.UUID `json:"id"`
+	Name  string     `json:"name"`
+	Limit int        `json:"limit" binding:"required,min=1"`
+}
+
+type ProductRequest struct {
+	Type        string                  `json:"type" binding:"required"`
+	Name        string                  `json:"name" binding:"required"`
+	Description string                  `json:"description"`
+	Price       int                     `json:"price" binding:"min=0"`
+	IsActive    *bool                   `json:"is_active"`
+	Variants    []ProductVariantRequest `json:"variants" binding:"required,min=1,dive"`
+}
+
+func bindProduct(c *gin.Context) (*ProductRequest, bool) {
+	var req ProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return nil, false
+	}
+
+	if !productTypes[req.Type] {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Type must be parking, merchandise or food.")
+		return nil, false
+	}
+
+	names := make(map[string]bool, len(req.Variants))
+	for _, variant := range req.Variants {
+		if names[variant.Name] {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Variant names must be unique.")
+			return nil, false
+		}
+		names[variant.Name] = true
+	}
+
+	return &req, true
+}
+
+// soldAddons returns how many units of each variant are held by pending or
+// paid checkouts.
+func soldAddons(gormDB *gorm.DB, variantIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ProductVariantID uuid.UUID
+		Sold             int
+	}
+	err := gormDB.Model(&models.AddonOrderItem{}).
+		Select("addon_order_items.product_variant_id, SUM(addon_order_items.quantity) AS sold").
+		Joins("JOIN payments ON payments.id = addon_order_items.payment_id AND payments.deleted_at IS NULL").
+		Where("addon_order_items.product_variant_id IN ? AND payments.status IN ?", variantIDs, activePaymentStatuses).
+		Group("addon_order_items.product_variant_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sold := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		sold[row.ProductVariantID] = row.Sold
+	}
+	return sold, nil
+}
+
+func applyVariantAvailability(gormDB *gorm.DB, products []models.Product) error {
+	var variantIDs []uuid.UUID
+	for _, product := range products {
+		for _, variant := range product.Variants {
+			variantIDs = append(variantIDs, variant.ID)
+		}
+	}
+	if len(variantIDs) == 0 {
+		return nil
+	}
+
+	sold, err := soldAddons(gormDB, variantIDs)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		for j := range products[i].Variants {
+			variant := &products[i].Variants[j]
+			variant.Available = max(variant.Limit-sold[variant.ID], 0)
+		}
+	}
+	return nil
+}
+
+func CreateProduct(c *gin.Context) {
+	req, ok := bindProduct(c)
+	if !ok {
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	product := models.Product{
+		EventID:     event.ID,
+		Type:        req.Type,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+	}
+	for _, variant := range req.Variants {
+		product.Variants = append(product.Variants, models.ProductVariant{
+			Name:  variant.Name,
+			Limit: variant.Limit,
+		})
+	}
+
+	if err := gormDB.Create(&product).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create product.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Product created successfully.",
+		"product_id": product.ID,
+	})
+}
+
+func UpdateProduct(c *gin.Context) {
+	req, ok := bindProduct(c)
+	if !ok {
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	if err := gormDB.Preload("Variants").Where("id = ? AND event_id = ?", c.Param("productId"), event.ID).First(&product).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Product not found.")
+		return
+	}
+
+	existing := make(map[uuid.UUID]models.ProductVariant, len(product.Variants))
+	var variantIDs []uuid.UUID
+	for _, variant := range product.Variants {
+		existing[variant.ID] = variant
+		variantIDs = append(variantIDs, variant.ID)
+	}
+
+	sold, err := soldAddons(gormDB, variantIDs)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error checking product inventory.")
+		return
+	}
+
+	var variants []models.ProductVariant
+	kept := map[uuid.UUID]bool{}
+	for _, variantReq := range req.Variants {
+		variant := models.ProductVariant{ProductID: product.ID}
+		if variantReq.ID != nil {
+			found, ok := existing[*variantReq.ID]
+			if !ok {
+				helpers.RespondWithError(c, http.StatusBadRequest, "Variant does not belong to this product.")
+				return
+			}
+			if variantReq.Limit < sold[found.ID] {
+				helpers.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("Limit for %q can't be lower than the %d already sold.", variantReq.Name, sold[found.ID]))
+				return
+			}
+			variant = found
+			kept[found.ID] = true
+		}
+		variant.Name = variantReq.Name
+		variant.Limit = variantReq.Limit
+		variants = append(variants, variant)
+	}
+
+	var removed []uuid.UUID
+	for _, variant := range product.Variants {
+		if kept[variant.ID] {
+			continue
+		}
+		if sold[variant.ID] > 0 {
+			helpers.RespondWithError(c, http.StatusConflict, "Variants that have been ordered can't be removed.")
+			return
+		}
+		removed = append(removed, variant.ID)
+	}
+
+	product.Type = req.Type
+	product.Name = req.Name
+	product.Description = req.Description
+	product.Price = req.Price
+	if req.IsActive != nil {
+		product.IsActive = *req.IsActive
+	}
+
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Variants").Save(&product).Error; err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			if err := tx.Where("id IN ?", removed).Delete(&models.ProductVariant{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range variants {
+			if err := tx.Save(&variants[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update product.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product updated successfully.",
+	})
+}
+
+func DeleteProduct(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	if err := gormDB.Where("id = ? AND event_id = ?", c.Param("productId"), event.ID).First(&product).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Product not found.")
+		return
+	}
+
+	var orders int64
+	gormDB.Model(&models.AddonOrderItem{}).
+		Joins("JOIN product_variants ON product_variants.id = addon_order_items.product_variant_id").
+		Where("product_variants.product_id = ?", product.ID).
+		Count(&orders)
+	if orders > 0 {
+		helpers.RespondWithError(c, http.StatusConflict, "Product has orders. Deactivate it instead.")
+		return
+	}
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductVariant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&product).Error
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to delete product.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product deleted successfully.",
+	})
+}
+
+func ListEventProducts(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var event models.Event
+	if err := gormDB.Where("id = ? AND status IN ?", c.Param("id"), publicEventStatuses).First(&event).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Event not found.")
+		return
+	}
+
+	var products []models.Product
+	err := gormDB.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("event_id = ? AND is_active = ?", event.ID, true).Order("type, name").Find(&products).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving products.")
+		return
+	}
+
+	if err := applyVariantAvailability(gormDB, products); err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error checking product inventory.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+	})
+}
+
+// prepareAddons checks the requested add-ons against the event's products and
+// their inventory, and returns the order items to attach to the payment.
+func prepareAddons(gormDB *gorm.DB, eventID uuid.UUID, reqs []AddonRequest) ([]models.AddonOrderItem, *checkoutError) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	var variantIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool, len(reqs))
+	for _, req := range reqs {
+		if seen[req.VariantID] {
+			return nil, &checkoutError{status: http.StatusBadRequest, message: "Each add-on can only be listed once."}
+		}
+		seen[req.VariantID] = true
+		variantIDs = append(variantIDs, req.VariantID)
+	}
+
+	var variants []models.ProductVariant
+	err := gormDB.Joins("Product").
+		Where("product_variants.id IN ?", variantIDs).
+		Where(`"Product".event_id = ? AND "Product".is_active = ?`, eventID, true).
+		Find(&variants).Error
+	if err != nil {
+		return nil, &checkoutError{status: http.StatusInternalServerError, message: "Error retrieving add-ons."}
+	}
+	if len(variants) != len(variantIDs) {
+		return nil, &checkoutError{status: http.StatusBadRequest, message: "One or more add-ons are not available for this event."}
+	}
+
+	variantsByID := make(map[uuid.UUID]*models.ProductVariant, len(variants))
+	for i := range variants {
+		variantsByID[variants[i].ID] = &variants[i]
+	}
+
+	items := make([]models.AddonOrderItem, 0, len(reqs))
+	for _, req := range reqs {
+		variant := variantsByID[req.VariantID]
+		items = append(items, models.AddonOrderItem{
+			ProductVariantID: variant.ID,
+			ProductVariant:   variant,
+			Quantity:         req.Quantity,
+			Price:            variant.Product.Price,
+		})
+	}
+	return items, nil
+}
+
+// reserveAddons checks add-on inventory for items with the variant rows
+// locked. It runs in the transaction that records the order items, so
+// concurrent checkouts can't both take the last units.
+func reserveAddons(tx *gorm.DB, items []models.AddonOrderItem) *checkoutError {
+	if len(items) == 0 {
+		return nil
+	}
+
+	variantIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		variantIDs = append(variantIDs, item.ProductVariantID)
+	}
+
+	var locked []models.ProductVariant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", variantIDs).Order("id").Find(&locked).Error
+	if err != nil {
+		return &checkoutError{status: http.StatusInternalServerError, message: "Error checking add-on inventory."}
+	}
+	limits := make(map[uuid.UUID]int, len(locked))
+	for _, variant := range locked {
+		limits[variant.ID] = variant.Limit
+	}
+
+	sold, err := soldAddons(tx, variantIDs)
+	if err != nil {
+		return &checkoutError{status: http.StatusInternalServerError, message: "Error checking add-on inventory."}
+	}
+
+	for _, item := range items {
+		if item.Quantity > limits[item.ProductVariantID]-sold[item.ProductVariantID] {
+			return &checkoutError{http.StatusBadRequest, codeAddonSoldOut, fmt.Sprintf("Not enough %s available.", addonName(item.ProductVariant))}
+		}
+	}
+	return nil
+}
+
+func addonName(variant *models.ProductVariant) string {
+	if variant.Name == "" {
+		return variant.Product.Name
+	}
+	return fmt.Sprintf("%s (%s)", variant.Product.Name, variant.Name)
+}
@@ -21,6 +21,14 @@ type EditProfileRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required,min=10,max=13"`
 }
 
+// profileResponse shows users their own contact and payout details, which are
+// hidden wherever else a user is serialized.
+type profileResponse struct {
+	models.User
+	PhoneNumber   string
+	AccountNumber *string
+}
+
 func GetProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -45,7 +53,7 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, profileResponse{User: user, PhoneNumber: user.PhoneNumber, AccountNumber: user.AccountNumber})
 }
 
 func ChangePassword(c *gin.Context) {
@@ -125,6 +133,9 @@ func EditProfile(c *gin.Context) {
 		return
 	}
 
+	if phoneNumber != user.PhoneNumber {
+		user.PhoneVerifiedAt = nil
+	}
 	user.Name = name
 	user.PhoneNumber = phoneNumber
 
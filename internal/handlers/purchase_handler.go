@@ -5,22 +5,34 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/middleware"
 	"github.com/farellandr/spoticket/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func generateQRCodeData(purchase *models.Purchase) string {
 	secretKey := os.Getenv("JWT_SECRET")
 	signature := generateSignature(purchase.ID, purchase.PaymentID, purchase.UserID, secretKey)
+	if purchase.SeatID != nil {
+		return fmt.Sprintf("purchase:%s;ticket:%s;event:%s;seat:%s;signature:%s",
+			purchase.ID.String(),
+			purchase.TicketID.String(),
+			purchase.Ticket.EventID.String(),
+			purchase.SeatID.String(),
+			signature,
+		)
+	}
 	return fmt.Sprintf("purchase:%s;ticket:%s;event:%s;signature:%s",
 		purchase.ID.String(),
 		purchase.TicketID.String(),
@@ -36,24 +48,172 @@ func generateSignature(purchaseID, paymentID, userID uuid.UUID, secretKey string
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-func extractPurchaseIDFromQRData(qrData string) (uuid.UUID, error) {
+func parseQRData(qrData, kind string) (map[string]string, error) {
 	parts := strings.Split(qrData, ";")
-	if len(parts) != 4 || !strings.HasPrefix(parts[0], "purchase:") || !strings.HasPrefix(parts[3], "signature:") {
-		return uuid.Nil, fmt.Errorf("invalid QR data format")
+	if len(parts) < 4 || !strings.HasPrefix(parts[0], kind+":") || !strings.HasPrefix(parts[len(parts)-1], "signature:") {
+		return nil, fmt.Errorf("invalid QR data format")
+	}
+
+	fields := make(map[string]string, len(parts))
+	for _, part := range parts {
+		key, value, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid QR data format")
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+func extractPurchaseIDFromQRData(qrData string) (uuid.UUID, error) {
+	fields, err := parseQRData(qrData, "purchase")
+	if err != nil {
+		return uuid.Nil, err
 	}
-	return uuid.Parse(strings.TrimPrefix(parts[0], "purchase:"))
+	return uuid.Parse(fields["purchase"])
 }
 
 func validateQRCodeSignature(purchase *models.Purchase, qrData string) bool {
-	parts := strings.Split(qrData, ";")
-	if len(parts) != 4 || !strings.HasPrefix(parts[3], "signature:") {
+	fields, err := parseQRData(qrData, "purchase")
+	if err != nil {
 		return false
 	}
 
 	secretKey := os.Getenv("JWT_SECRET")
-	signature := strings.TrimPrefix(parts[3], "signature:")
 	expectedSignature := generateSignature(purchase.ID, purchase.PaymentID, purchase.UserID, secretKey)
-	return hmac.Equal([]byte(expectedSignature), []byte(signature))
+	return hmac.Equal([]byte(expectedSignature), []byte(fields["signature"]))
+}
+
+func purchaseSeat(purchase *models.Purchase) gin.H {
+	if purchase.Seat == nil {
+		return nil
+	}
+	return gin.H{
+		"id":            purchase.Seat.ID,
+		"section":       purchase.Seat.Section.Name,
+		"row":           purchase.Seat.Row,
+		"number":        purchase.Seat.Number,
+		"label":         seatLabel(purchase.Seat.Section.Name, purchase.Seat),
+		"is_accessible": purchase.Seat.IsAccessible,
+	}
+}
+
+func GetPurchase(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User not authenticated.")
+		return
+	}
+
+	purchaseID, err := uuid.Parse(c.Param("purchaseId"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid purchase ID")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var purchase models.Purchase
+	if err := gormDB.Preload("Ticket.Event").Preload("Payment").Preload("Attendee").Preload("Seat.Section").First(&purchase, purchaseID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found")
+		return
+	}
+
+	if purchase.UserID != userID {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to view this purchase")
+		return
+	}
+
+	var qrData *string
+	if purchase.Payment.Status == "PAID" {
+		data := generateQRCodeData(&purchase)
+		qrData = &data
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"purchase_id": purchase.ID,
+		"event": gin.H{
+			"id":         purchase.Ticket.Event.ID,
+			"title":      purchase.Ticket.Event.Title,
+			"start_time": purchase.Ticket.Event.StartTime,
+			"end_time":   purchase.Ticket.Event.EndTime,
+			"location":   purchase.Ticket.Event.Location,
+		},
+		"ticket_type": purchase.Ticket.Type,
+		"is_used":     purchase.IsUsed,
+		"attendee":    purchase.Attendee,
+		"seat":        purchaseSeat(&purchase),
+		"qr_data":     qrData,
+	})
+}
+
+func AssignAttendee(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User not authenticated.")
+		return
+	}
+
+	purchaseID, err := uuid.Parse(c.Param("purchaseId"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid purchase ID")
+		return
+	}
+
+	var req AttendeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var purchase models.Purchase
+	if err := gormDB.Preload("Attendee").First(&purchase, purchaseID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found")
+		return
+	}
+
+	if purchase.UserID != userID {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to update this purchase")
+		return
+	}
+
+	if purchase.IsUsed || purchase.EntryCount > 0 {
+		helpers.RespondWithError(c, http.StatusForbidden, "Ticket already used")
+		return
+	}
+
+	attendee := purchase.Attendee
+	if attendee == nil {
+		attendee = &models.Attendee{
+			PaymentID:  purchase.PaymentID,
+			PurchaseID: &purchase.ID,
+		}
+	}
+	attendee.Name = req.Name
+	attendee.Email = req.Email
+	attendee.IDNumber = req.IDNumber
+
+	if err := gormDB.Save(attendee).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to save attendee details")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Attendee details saved successfully",
+		"attendee": attendee,
+	})
 }
 
 func GenerateTicketQR(c *gin.Context) {
@@ -78,7 +238,7 @@ func GenerateTicketQR(c *gin.Context) {
 	gormDB := db.(*gorm.DB)
 
 	var purchase models.Purchase
-	if err := gormDB.Preload("Ticket.Event").First(&purchase, purchaseID).Error; err != nil {
+	if err := gormDB.Preload("Ticket.Event").Preload("Payment").First(&purchase, purchaseID).Error; err != nil {
 		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found")
 		return
 	}
@@ -114,12 +274,73 @@ func GenerateTicketQR(c *gin.Context) {
 	c.Data(http.StatusOK, "image/png", qrImage)
 }
 
+// eventDay truncates t to midnight in the event's timezone, so daily entries
+// reset when the local day changes rather than at midnight UTC.
+func eventDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// entriesRemaining returns how many entries the purchase still allows, or -1
+// for unlimited re-entry. A non-empty denial means the holder cannot enter now.
+func entriesRemaining(gormDB *gorm.DB, purchase *models.Purchase, now time.Time) (int, string, error) {
+	ticket := purchase.Ticket
+
+	switch ticket.EntryPolicy {
+	case models.EntryPolicyMulti:
+		remaining := ticket.MaxEntries - purchase.EntryCount
+		if remaining <= 0 {
+			return 0, "No entries remaining", nil
+		}
+		return remaining, "", nil
+
+	case models.EntryPolicyDaily:
+		loc := ticket.Event.TimeLocation()
+		today := eventDay(now, loc)
+		firstDay := eventDay(ticket.Event.StartTime, loc)
+		lastDay := eventDay(ticket.Event.EndTime, loc)
+		if today.Before(firstDay) || today.After(lastDay) {
+			return 0, "Ticket is not valid today", nil
+		}
+
+		remaining := int(math.Round(lastDay.Sub(today).Hours()/24)) + 1
+
+		var enteredToday int64
+		if err := gormDB.Model(&models.CheckIn{}).
+			Where("purchase_id = ? AND direction = ? AND created_at >= ?", purchase.ID, models.CheckInDirectionIn, today).
+			Count(&enteredToday).Error; err != nil {
+			return 0, "", err
+		}
+		if enteredToday > 0 {
+			return remaining - 1, "Ticket already used today", nil
+		}
+		return remaining, "", nil
+
+	case models.EntryPolicyUnlimited:
+		if purchase.IsInside {
+			return -1, "Ticket holder is already inside", nil
+		}
+		return -1, "", nil
+
+	default:
+		if purchase.IsUsed || purchase.EntryCount > 0 {
+			return 0, "Ticket already used", nil
+		}
+		return 1, "", nil
+	}
+}
+
 func ValidateTicket(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		helpers.RespondWithError(c, http.StatusUnauthorized, "User not authenticated.")
 		return
 	}
+	staffID, ok := userID.(uuid.UUID)
+	if !ok {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID type.")
+		return
+	}
 
 	db, exists := c.Get("db")
 	if !exists {
@@ -129,13 +350,25 @@ func ValidateTicket(c *gin.Context) {
 	gormDB := db.(*gorm.DB)
 
 	var validationRequest struct {
-		QRData string `json:"qr_data" binding:"required"`
+		QRData    string `json:"qr_data" binding:"required"`
+		Direction string `json:"direction"`
+		Gate      string `json:"gate"`
+		Section   string `json:"section"`
 	}
 	if err := c.ShouldBindJSON(&validationRequest); err != nil {
 		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
+	direction := validationRequest.Direction
+	if direction == "" {
+		direction = models.CheckInDirectionIn
+	}
+	if direction != models.CheckInDirectionIn && direction != models.CheckInDirectionOut {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Direction must be either in or out")
+		return
+	}
+
 	purchaseID, err := extractPurchaseIDFromQRData(validationRequest.QRData)
 	if err != nil {
 		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid QR code format")
@@ -143,7 +376,7 @@ func ValidateTicket(c *gin.Context) {
 	}
 
 	var purchase models.Purchase
-	if err := gormDB.Preload("Ticket.Event").First(&purchase, purchaseID).Error; err != nil {
+	if err := gormDB.Preload("Ticket.Event").Preload("Attendee").Preload("Seat.Section").First(&purchase, purchaseID).Error; err != nil {
 		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found")
 		return
 	}
@@ -153,26 +386,120 @@ func ValidateTicket(c *gin.Context) {
 		return
 	}
 
-	if purchase.Ticket.Event.UserID != userID {
+	if purchase.Ticket.Event.UserID != staffID {
 		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to validate this ticket")
 		return
 	}
 
-	if purchase.IsUsed {
-		helpers.RespondWithError(c, http.StatusForbidden, "Ticket already used")
+	if direction == models.CheckInDirectionOut && purchase.Ticket.EntryPolicy != models.EntryPolicyUnlimited {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Exit tracking is only available for unlimited re-entry tickets")
 		return
 	}
 
-	if err := gormDB.Model(&purchase).Update("is_used", true).Error; err != nil {
+	if validationRequest.Section != "" && purchase.Seat != nil && !strings.EqualFold(purchase.Seat.Section.Name, validationRequest.Section) {
+		helpers.RespondWithError(c, http.StatusForbidden, fmt.Sprintf("Ticket is for section %s", purchase.Seat.Section.Name))
+		return
+	}
+
+	var remaining int
+	var denial string
+	var checkIn models.CheckIn
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Purchase
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, purchase.ID).Error; err != nil {
+			return err
+		}
+		purchase.IsUsed = locked.IsUsed
+		purchase.EntryCount = locked.EntryCount
+		purchase.IsInside = locked.IsInside
+
+		var listingCount int64
+		if err := tx.Model(&models.ResaleListing{}).Where("purchase_id = ? AND status = ?", purchase.ID, models.ResaleStatusListed).Count(&listingCount).Error; err != nil {
+			return err
+		}
+		if listingCount > 0 {
+			denial = "Ticket is listed for resale"
+			return nil
+		}
+
+		now := time.Now()
+		if direction == models.CheckInDirectionOut {
+			if !purchase.IsInside {
+				denial = "Ticket holder is not inside"
+				return nil
+			}
+			remaining = -1
+			purchase.IsInside = false
+		} else {
+			var err error
+			remaining, denial, err = entriesRemaining(tx, &purchase, now)
+			if err != nil || denial != "" {
+				return err
+			}
+
+			purchase.EntryCount++
+			if purchase.Ticket.EntryPolicy == models.EntryPolicyUnlimited {
+				purchase.IsInside = true
+			} else {
+				remaining--
+				purchase.IsUsed = remaining == 0
+			}
+		}
+
+		checkIn = models.CheckIn{
+			PurchaseID: purchase.ID,
+			Direction:  direction,
+			Gate:       validationRequest.Gate,
+			ScannedBy:  staffID,
+			CreatedAt:  now,
+		}
+		if err := tx.Create(&checkIn).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&purchase).Updates(map[string]interface{}{
+			"is_used":     purchase.IsUsed,
+			"entry_count": purchase.EntryCount,
+			"is_inside":   purchase.IsInside,
+		}).Error
+	})
+	if err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to validate ticket")
 		return
 	}
+	if denial != "" {
+		helpers.RespondWithError(c, http.StatusForbidden, denial)
+		return
+	}
+
+	if hub := middleware.GetCheckInHub(c); hub != nil {
+		hub.Publish(helpers.CheckInEvent{
+			EventID:    purchase.Ticket.EventID,
+			PurchaseID: purchase.ID,
+			TicketID:   purchase.TicketID,
+			TicketType: purchase.Ticket.Type,
+			Direction:  checkIn.Direction,
+			Gate:       checkIn.Gate,
+			ScannedAt:  checkIn.CreatedAt,
+		})
+	}
+
+	var remainingEntries *int
+	if remaining >= 0 {
+		remainingEntries = &remaining
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Ticket validated successfully",
 		"ticket": gin.H{
-			"event_title": purchase.Ticket.Event.Title,
-			"ticket_type": purchase.Ticket.Type,
+			"event_title":       purchase.Ticket.Event.Title,
+			"ticket_type":       purchase.Ticket.Type,
+			"entry_policy":      purchase.Ticket.EntryPolicy,
+			"direction":         direction,
+			"entry_count":       purchase.EntryCount,
+			"remaining_entries": remainingEntries,
+			"attendee":          purchase.Attendee,
+			"seat":              purchaseSeat(&purchase),
 		},
 	})
 }
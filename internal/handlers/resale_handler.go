@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/middleware"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/xendit/xendit-go/v6"
+	"github.com/xendit/xendit-go/v6/invoice"
+	"github.com/xendit/xendit-go/v6/payout"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	resaleFeePercent        = 5.0
+	resaleReservationWindow = 15 * time.Minute
+)
+
+var (
+	errListingUnavailable = errors.New("listing is no longer available")
+	errPaymentProcessed   = errors.New("payment already processed")
+)
+
+type ResaleListingRequest struct {
+	PurchaseID uuid.UUID `json:"purchase_id" binding:"required"`
+	Price      int       `json:"price" binding:"required,min=1"`
+}
+
+func resaleBlockReason(purchase *models.Purchase) string {
+	if !purchase.Ticket.Event.ResaleEnabled {
+		return "Resale is disabled for this event."
+	}
+	return ownershipBlockReason(purchase)
+}
+
+func ListResaleListings(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	pagination, err := helpers.ParsePagination(c)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := gormDB.Model(&models.ResaleListing{}).
+		Where("resale_listings.status = ? AND (resale_listings.reserved_until IS NULL OR resale_listings.reserved_until < ?)", models.ResaleStatusListed, time.Now())
+	if eventID := c.Query("event_id"); eventID != "" {
+		query = query.
+			Joins("JOIN purchases ON purchases.id = resale_listings.purchase_id").
+			Joins("JOIN tickets ON tickets.id = purchases.ticket_id").
+			Where("tickets.event_id = ?", eventID)
+	}
+
+	result, err := pagination.Fetch(query, "resale_listings.id", []helpers.SortKey{{Column: "resale_listings.created_at", Desc: true}}, nil)
+	if err != nil {
+		helpers.RespondWithPageError(c, err, "Error retrieving resale listings.")
+		return
+	}
+
+	var listings []models.ResaleListing
+	if err := gormDB.Preload("Purchase.Ticket.Event").Where("id IN ?", result.IDs).Find(&listings).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving resale listings.")
+		return
+	}
+
+	response := pagination.Response(result)
+	response["listings"] = helpers.SortByIDs(listings, result.IDs, func(listing *models.ResaleListing) uuid.UUID { return listing.ID })
+	c.JSON(http.StatusOK, response)
+}
+
+func CreateResaleListing(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	var req ResaleListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var seller models.User
+	if err := gormDB.Where("id = ?", userID).First(&seller).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	if seller.AccountChannel == nil || seller.AccountNumber == nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Add a payout account to your profile before reselling tickets.")
+		return
+	}
+
+	var listing models.ResaleListing
+	var blockReason string
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		var purchase models.Purchase
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&purchase, req.PurchaseID).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Ticket.Event").Preload("Payment").First(&purchase, purchase.ID).Error; err != nil {
+			return err
+		}
+
+		if purchase.UserID != seller.ID {
+			blockReason = "You don't have permission to resell this ticket."
+			return nil
+		}
+		if blockReason = resaleBlockReason(&purchase); blockReason != "" {
+			return nil
+		}
+
+		maxPrice := purchase.Ticket.Price * (100 + purchase.Ticket.Event.ResaleMaxMarkup) / 100
+		if req.Price > maxPrice {
+			blockReason = fmt.Sprintf("Resale price can't exceed %d.", maxPrice)
+			return nil
+		}
+
+		var openCount int64
+		tx.Model(&models.Transfer{}).Where("purchase_id = ? AND status = ?", purchase.ID, models.TransferStatusPending).Count(&openCount)
+		if openCount > 0 {
+			blockReason = "This ticket has a pending transfer."
+			return nil
+		}
+		tx.Model(&models.ResaleListing{}).Where("purchase_id = ? AND status = ?", purchase.ID, models.ResaleStatusListed).Count(&openCount)
+		if openCount > 0 {
+			blockReason = "This ticket is already listed for resale."
+			return nil
+		}
+
+		listing = models.ResaleListing{
+			ID:         uuid.New(),
+			PurchaseID: purchase.ID,
+			SellerID:   seller.ID,
+			Price:      req.Price,
+			Status:     models.ResaleStatusListed,
+		}
+		return tx.Create(&listing).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found.")
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create resale listing.")
+		return
+	}
+	if blockReason != "" {
+		helpers.RespondWithError(c, http.StatusForbidden, blockReason)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Ticket listed for resale successfully.",
+		"listing_id": listing.ID,
+	})
+}
+
+func CancelResaleListing(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	result := gormDB.Model(&models.ResaleListing{}).
+		Where("id = ? AND seller_id = ? AND status = ?", c.Param("id"), userID, models.ResaleStatusListed).
+		Where("reserved_until IS NULL OR reserved_until < ?", time.Now()).
+		Update("status", models.ResaleStatusCancelled)
+	if result.Error != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to cancel resale listing.")
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		helpers.RespondWithError(c, http.StatusForbidden, "Listing not found, already reserved by a buyer, or you don't have permission to cancel it.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Resale listing cancelled successfully.",
+	})
+}
+
+func BuyResaleListing(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	xenditClient := middleware.GetXenditClient(c)
+	if xenditClient == nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Xendit client not initialized.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var buyer models.User
+	if err := gormDB.Where("id = ?", userID).First(&buyer).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	var listing models.ResaleListing
+	if err := gormDB.Preload("Purchase.Ticket.Event.Categories").Where("id = ?", c.Param("id")).First(&listing).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Listing not found.")
+		return
+	}
+
+	if listing.SellerID == buyer.ID {
+		helpers.RespondWithError(c, http.StatusBadRequest, "You can't buy your own listing.")
+		return
+	}
+
+	if listing.Purchase.Ticket.Event.Status != models.EventStatusPublished {
+		helpers.RespondWithError(c, http.StatusForbidden, "Event is not on sale.")
+		return
+	}
+
+	externalID := fmt.Sprintf("RSL-%d-%s", time.Now().Unix(), helpers.EncryptExternalID(listing.ID, nil))
+	reservedUntil := time.Now().Add(resaleReservationWindow)
+
+	result := gormDB.Model(&models.ResaleListing{}).
+		Where("id = ? AND status = ?", listing.ID, models.ResaleStatusListed).
+		Where("reserved_until IS NULL OR reserved_until < ?", time.Now()).
+		Updates(map[string]interface{}{
+			"buyer_id":       buyer.ID,
+			"transaction_id": externalID,
+			"reserved_until": reservedUntil,
+		})
+	if result.Error != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to reserve listing.")
+		return
+	}
+	if result.RowsAffected == 0 {
+		helpers.RespondWithError(c, http.StatusConflict, "This listing is no longer available.")
+		return
+	}
+
+	ticket := listing.Purchase.Ticket
+	adminFeePercent := 1.5
+	adminFee := int(float64(listing.Price) * adminFeePercent / 100)
+	descStr := fmt.Sprintf("%s - %s (Resale)", ticket.Event.Title, ticket.Type)
+	invoiceDuration := strconv.Itoa(int(resaleReservationWindow.Seconds()))
+
+	invoiceRequest := invoice.CreateInvoiceRequest{
+		ExternalId:      externalID,
+		Amount:          float64(listing.Price + adminFee),
+		PayerEmail:      &buyer.Email,
+		Description:     &descStr,
+		InvoiceDuration: &invoiceDuration,
+		Customer: &invoice.CustomerObject{
+			GivenNames:   *invoice.NewNullableString(&buyer.Name),
+			Email:        *invoice.NewNullableString(&buyer.Email),
+			MobileNumber: *invoice.NewNullableString(&buyer.PhoneNumber),
+		},
+		Fees: []invoice.InvoiceFee{
+			{
+				Type:  fmt.Sprintf("Admin Fee (%.1f%%)", adminFeePercent),
+				Value: float32(adminFee),
+			},
+		},
+		Items: []invoice.InvoiceItem{
+			{
+				Name:     descStr,
+				Quantity: 1,
+				Price:    float32(listing.Price),
+			},
+		},
+	}
+
+	resp, _, xndErr := xenditClient.InvoiceApi.CreateInvoice(context.Background()).CreateInvoiceRequest(invoiceRequest).Execute()
+	if xndErr != nil {
+		gormDB.Model(&models.ResaleListing{}).Where("id = ? AND transaction_id = ?", listing.ID, externalID).
+			Updates(map[string]interface{}{"buyer_id": nil, "transaction_id": nil, "reserved_until": nil})
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create payment link.")
+		return
+	}
+
+	payment := models.Payment{
+		Amount:          listing.Price + adminFee,
+		AdminFee:        adminFee,
+		Status:          "PENDING",
+		TransactionID:   externalID,
+		UserID:          buyer.ID,
+		TicketID:        &ticket.ID,
+		Quantity:        1,
+		ResaleListingID: &listing.ID,
+	}
+	if err := gormDB.Create(&payment).Error; err != nil {
+		// Without a payment the webhook can't match the invoice to this
+		// buyer, so it must not stay payable.
+		if resp.Id != nil {
+			if _, _, err := xenditClient.InvoiceApi.ExpireInvoice(context.Background(), *resp.Id).Execute(); err != nil {
+				fmt.Printf("Error expiring resale invoice %s: %v\n", externalID, err)
+			}
+		}
+		gormDB.Model(&models.ResaleListing{}).Where("id = ? AND transaction_id = ?", listing.ID, externalID).
+			Updates(map[string]interface{}{"buyer_id": nil, "transaction_id": nil, "reserved_until": nil})
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to record payment.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payment_url":    resp.InvoiceUrl,
+		"reserved_until": reservedUntil,
+	})
+}
+
+// handleResalePayment moves a resold ticket to its buyer once the invoice is
+// paid. The purchase is re-pointed at the buyer's payment, which changes the
+// QR signature inputs and invalidates the seller's copy.
+//
+// The listing is found through the buyer's payment rather than the listing's
+// transaction_id, which is overwritten when a lapsed reservation is taken by
+// someone else. A buyer who pays after losing the listing is refunded.
+func handleResalePayment(c *gin.Context, gormDB *gorm.DB, xenditClient *xendit.APIClient, payload *invoice.InvoiceCallback) {
+	var payment models.Payment
+	if err := gormDB.Where("transaction_id = ?", payload.ExternalId).First(&payment).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Payment not found.")
+		return
+	}
+
+	if payment.Status == "PAID" || payment.Status == "REFUNDED" {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Payment already processed",
+		})
+		return
+	}
+
+	listingID := payment.ResaleListingID
+	if listingID == nil {
+		id, _, err := helpers.ExtractTicketID(payload.ExternalId)
+		if err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Invalid external ID.")
+			return
+		}
+		listingID = &id
+	}
+
+	var listing models.ResaleListing
+	if err := gormDB.Preload("Seller").First(&listing, *listingID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Resale listing not found.")
+		return
+	}
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&listing, listing.ID).Error; err != nil {
+			return err
+		}
+		if listing.Status != models.ResaleStatusListed || listing.BuyerID == nil || *listing.BuyerID != payment.UserID ||
+			listing.TransactionID == nil || *listing.TransactionID != payment.TransactionID {
+			return errListingUnavailable
+		}
+
+		var purchase models.Purchase
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&purchase, listing.PurchaseID).Error; err != nil {
+			return err
+		}
+		if purchase.UserID != listing.SellerID || purchase.IsUsed || purchase.EntryCount > 0 {
+			return errListingUnavailable
+		}
+
+		result := tx.Model(&payment).Where("status <> ?", "PAID").Updates(map[string]interface{}{
+			"amount": int(payload.Amount),
+			"method": *payload.PaymentMethod,
+			"status": payload.Status,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPaymentProcessed
+		}
+
+		if err := tx.Model(&purchase).Updates(map[string]interface{}{
+			"user_id":    *listing.BuyerID,
+			"payment_id": payment.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("purchase_id = ?", purchase.ID).Delete(&models.Attendee{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&listing).Updates(map[string]interface{}{
+			"status":  models.ResaleStatusSold,
+			"sold_at": time.Now(),
+		}).Error
+	})
+	if errors.Is(err, errPaymentProcessed) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Payment already processed",
+		})
+		return
+	}
+	if errors.Is(err, errListingUnavailable) {
+		if err := refundPayment(gormDB, xenditClient, payload, &payment, fmt.Sprintf("resale-refund-%s", payment.ID)); err != nil {
+			fmt.Printf("Error refunding resale payment %s: %v\n", payment.TransactionID, err)
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Resale listing is no longer available and the refund failed.")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Resale listing is no longer available. Payment refunded.",
+		})
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to complete resale.")
+		return
+	}
+
+	if listing.Seller.AccountChannel == nil || listing.Seller.AccountNumber == nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Seller has no payout account.")
+		return
+	}
+
+	resaleFee := int(float64(listing.Price) * resaleFeePercent / 100)
+	payoutRequest := *payout.NewCreatePayoutRequest(
+		fmt.Sprintf("resale-%s", listing.ID),
+		*listing.Seller.AccountChannel,
+		payout.DigitalPayoutChannelProperties{
+			AccountHolderName: *payout.NewNullableString(listing.Seller.AccountName),
+			AccountNumber:     *listing.Seller.AccountNumber,
+		},
+		float32(listing.Price-resaleFee),
+		"IDR",
+	)
+
+	resp, _, xndErr := xenditClient.PayoutApi.CreatePayout(context.Background()).
+		IdempotencyKey(fmt.Sprintf("resale-%s", listing.ID)).
+		CreatePayoutRequest(payoutRequest).
+		Execute()
+	if xndErr != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create payout.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Resale payout created to channel: %s", resp.Payout.ChannelCode),
+	})
+}
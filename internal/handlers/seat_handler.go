@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SeatRowRequest struct {
+	Label           string `json:"label" binding:"required"`
+	Seats           int    `json:"seats" binding:"required,min=1"`
+	AccessibleSeats []int  `json:"accessible_seats"`
+}
+
+type SeatSectionRequest struct {
+	Name string           `json:"name" binding:"required"`
+	Rows []SeatRowRequest `json:"rows" binding:"required,min=1,dive"`
+}
+
+type SeatMapRequest struct {
+	Sections []SeatSectionRequest `json:"sections" binding:"required,min=1,dive"`
+}
+
+type SeatTierRequest struct {
+	TicketID *uuid.UUID  `json:"ticket_id"`
+	SeatIDs  []uuid.UUID `json:"seat_ids" binding:"required,min=1"`
+}
+
+type seatView struct {
+	ID           uuid.UUID  `json:"id"`
+	Row          string     `json:"row"`
+	Number       int        `json:"number"`
+	Label        string     `json:"label"`
+	IsAccessible bool       `json:"is_accessible"`
+	TicketID     *uuid.UUID `json:"ticket_id"`
+	Status       string     `json:"status"`
+}
+
+func seatLabel(section string, seat *models.Seat) string {
+	return fmt.Sprintf("%s / Row %s / Seat %d", section, seat.Row, seat.Number)
+}
+
+func seatStatus(seat *models.Seat, now time.Time) string {
+	switch {
+	case seat.PurchaseID != nil:
+		return "sold"
+	case seat.HeldUntil != nil && seat.HeldUntil.After(now):
+		return "held"
+	case seat.TicketID == nil:
+		return "unassigned"
+	default:
+		return "available"
+	}
+}
+
+func buildSeatSections(req SeatMapRequest) ([]models.SeatSection, error) {
+	sections := make([]models.SeatSection, 0, len(req.Sections))
+	for _, sectionReq := range req.Sections {
+		section := models.SeatSection{Name: sectionReq.Name}
+
+		for _, rowReq := range sectionReq.Rows {
+			accessible := make(map[int]bool, len(rowReq.AccessibleSeats))
+			for _, number := range rowReq.AccessibleSeats {
+				if number < 1 || number > rowReq.Seats {
+					return nil, fmt.Errorf("accessible seat %d is outside row %s", number, rowReq.Label)
+				}
+				accessible[number] = true
+			}
+
+			for number := 1; number <= rowReq.Seats; number++ {
+				section.Seats = append(section.Seats, models.Seat{
+					Row:          rowReq.Label,
+					Number:       number,
+					IsAccessible: accessible[number],
+				})
+			}
+		}
+
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+func eventSeatsLocked(gormDB *gorm.DB, eventID uuid.UUID) bool {
+	var lockedSeats int64
+	gormDB.Model(&models.Seat{}).
+		Joins("JOIN seat_sections ON seat_sections.id = seats.section_id").
+		Where("seat_sections.event_id = ?", eventID).
+		Where("seats.purchase_id IS NOT NULL OR seats.held_until > ?", time.Now()).
+		Count(&lockedSeats)
+	return lockedSeats > 0
+}
+
+func SaveEventSeatMap(c *gin.Context) {
+	var req SeatMapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	sections, err := buildSeatSections(req)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	for i := range sections {
+		sections[i].EventID = &event.ID
+	}
+
+	if eventSeatsLocked(gormDB, event.ID) {
+		helpers.RespondWithError(c, http.StatusConflict, "Seats have already been sold or held for this event.")
+		return
+	}
+
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", event.ID).Delete(&models.SeatSection{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&sections).Error
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to save seat map.")
+		return
+	}
+
+	seatCount := 0
+	for _, section := range sections {
+		seatCount += len(section.Seats)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Seat map saved successfully.",
+		"sections": len(sections),
+		"seats":    seatCount,
+	})
+}
+
+func GetEventSeatMap(c *gin.Context) {
+	eventID := c.Param("id")
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var sections []models.SeatSection
+	err := gormDB.Preload("Seats", func(db *gorm.DB) *gorm.DB {
+		return db.Order("row, number")
+	}).Where("event_id = ?", eventID).Order("name").Find(&sections).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving seat map.")
+		return
+	}
+
+	now := time.Now()
+	response := make([]gin.H, 0, len(sections))
+	for _, section := range sections {
+		seats := make([]seatView, 0, len(section.Seats))
+		for i := range section.Seats {
+			seat := &section.Seats[i]
+			seats = append(seats, seatView{
+				ID:           seat.ID,
+				Row:          seat.Row,
+				Number:       seat.Number,
+				Label:        seatLabel(section.Name, seat),
+				IsAccessible: seat.IsAccessible,
+				TicketID:     seat.TicketID,
+				Status:       seatStatus(seat, now),
+			})
+		}
+
+		response = append(response, gin.H{
+			"id":    section.ID,
+			"name":  section.Name,
+			"seats": seats,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id": eventID,
+		"sections": response,
+	})
+}
+
+func AssignSeatTier(c *gin.Context) {
+	var req SeatTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	if req.TicketID != nil {
+		var ticket models.Ticket
+		if err := gormDB.Where("id = ? AND event_id = ?", *req.TicketID, event.ID).First(&ticket).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Ticket does not belong to this event.")
+			return
+		}
+	}
+
+	result := gormDB.Model(&models.Seat{}).
+		Where("id IN ? AND purchase_id IS NULL", req.SeatIDs).
+		Where("section_id IN (?)", gormDB.Model(&models.SeatSection{}).Select("id").Where("event_id = ?", event.ID)).
+		Update("ticket_id", req.TicketID)
+	if result.Error != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to assign seats.")
+		return
+	}
+
+	if int(result.RowsAffected) != len(req.SeatIDs) {
+		helpers.RespondWithError(c, http.StatusConflict, fmt.Sprintf("Only %d of %d seats could be assigned; the rest are sold or not part of this event.", result.RowsAffected, len(req.SeatIDs)))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Seats assigned successfully.",
+	})
+}
+
+// holdSeats locks the requested seats for a checkout. Seats under a live hold
+// are rejected even when the same user holds them, since the older checkout's
+// invoice can still be paid and needs its seats.
+func holdSeats(gormDB *gorm.DB, ticketID, userID uuid.UUID, seatIDs []uuid.UUID, reference string, until time.Time) (bool, error) {
+	now := time.Now()
+	result := gormDB.Model(&models.Seat{}).
+		Where("id IN ? AND ticket_id = ? AND purchase_id IS NULL", seatIDs, ticketID).
+		Where("held_until IS NULL OR held_until < ?", now).
+		Updates(map[string]interface{}{
+			"held_by_user_id": userID,
+			"held_until":      until,
+			"hold_reference":  reference,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if int(result.RowsAffected) != len(seatIDs) {
+		releaseSeats(gormDB, reference)
+		return false, nil
+	}
+	return true, nil
+}
+
+func releaseSeats(gormDB *gorm.DB, reference string) error {
+	return gormDB.Model(&models.Seat{}).
+		Where("hold_reference = ? AND purchase_id IS NULL", reference).
+		Updates(map[string]interface{}{
+			"held_by_user_id": nil,
+			"held_until":      nil,
+			"hold_reference":  nil,
+		}).Error
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxSeriesOccurrences = 100
+
+func CreateSeries(c *gin.Context) {
+	title := c.PostForm("title")
+	description := c.PostForm("description")
+
+	startTime, err := time.Parse(time.RFC3339, c.PostForm("start_time"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid start time format.")
+		return
+	}
+	endTime, err := time.Parse(time.RFC3339, c.PostForm("end_time"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid end time format.")
+		return
+	}
+	if !endTime.After(startTime) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "End time must be after start time.")
+		return
+	}
+
+	rule, err := helpers.ParseRRule(c.PostForm("rrule"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid recurrence rule: %s.", err))
+		return
+	}
+
+	latitude, err := parseCoordinate(c.PostForm("latitude"), 90)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid latitude.")
+		return
+	}
+	longitude, err := parseCoordinate(c.PostForm("longitude"), 180)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid longitude.")
+		return
+	}
+
+	var categories []string
+	for i := 0; ; i++ {
+		category := c.PostForm(fmt.Sprintf("categories[%d]", i))
+		if category == "" {
+			break
+		}
+		categories = append(categories, category)
+	}
+
+	var ticketReqs []TicketRequest
+	if err := json.Unmarshal([]byte(c.DefaultPostForm("tickets", "[]")), &ticketReqs); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid ticket templates.")
+		return
+	}
+
+	if title == "" || description == "" || len(categories) == 0 || len(ticketReqs) == 0 {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Missing required fields.")
+		return
+	}
+
+	ticketLimit := 0
+	for i := range ticketReqs {
+		if ticketReqs[i].Type == "" || ticketReqs[i].Limit < 1 {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Every ticket template needs a type and a limit of at least 1.")
+			return
+		}
+		if msg := validateEntryPolicy(&ticketReqs[i]); msg != "" {
+			helpers.RespondWithError(c, http.StatusBadRequest, msg)
+			return
+		}
+		if msg := validatePurchaseLimits(&ticketReqs[i]); msg != "" {
+			helpers.RespondWithError(c, http.StatusBadRequest, msg)
+			return
+		}
+		ticketLimit += ticketReqs[i].Limit
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var user models.User
+	if err := gormDB.Where("id = ?", userID).Preload("Role").First(&user).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	if user.Role.Name != "organizer" {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "You have no permission to create an event.")
+		return
+	}
+
+	series := models.EventSeries{
+		ID:              uuid.New(),
+		Title:           title,
+		Description:     description,
+		RRule:           c.PostForm("rrule"),
+		FirstStartTime:  startTime,
+		DurationMinutes: int(endTime.Sub(startTime).Minutes()),
+		Province:        c.PostForm("province"),
+		City:            c.PostForm("city"),
+		District:        c.PostForm("district"),
+		SubDistrict:     c.PostForm("sub_district"),
+		Location:        c.PostForm("location"),
+		Latitude:        latitude,
+		Longitude:       longitude,
+		UserID:          user.ID,
+	}
+
+	if venueID := c.PostForm("venue_id"); venueID != "" {
+		var venue models.Venue
+		if err := gormDB.Where("id = ?", venueID).First(&venue).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, "Venue not found.")
+			return
+		}
+		if venue.Capacity > 0 && ticketLimit > venue.Capacity {
+			helpers.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("Ticket limits exceed the venue capacity of %d.", venue.Capacity))
+			return
+		}
+
+		series.VenueID = &venue.ID
+		series.Province = venue.Province
+		series.City = venue.City
+		series.District = venue.District
+		series.SubDistrict = venue.SubDistrict
+		series.Location = venue.Name
+		if venue.Latitude != nil && venue.Longitude != nil {
+			series.Latitude = venue.Latitude
+			series.Longitude = venue.Longitude
+		}
+	}
+
+	for _, categoryName := range categories {
+		var category models.Category
+		if err := gormDB.Where("name = ?", categoryName).FirstOrCreate(&category, models.Category{Name: categoryName}).Error; err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error processing categories.")
+			return
+		}
+		series.Categories = append(series.Categories, category)
+	}
+
+	for _, req := range ticketReqs {
+		series.TicketTemplates = append(series.TicketTemplates, models.SeriesTicketTemplate{
+			Type:                   req.Type,
+			Price:                  req.Price,
+			Limit:                  req.Limit,
+			EntryPolicy:            req.EntryPolicy,
+			MaxEntries:             req.MaxEntries,
+			RequireAttendeeDetails: req.RequireAttendeeDetails,
+			MinPerOrder:            req.MinPerOrder,
+			MaxPerOrder:            req.MaxPerOrder,
+			MaxPerUser:             req.MaxPerUser,
+			RequireVerifiedPhone:   req.RequireVerifiedPhone,
+			OnePerPhone:            req.OnePerPhone,
+		})
+	}
+
+	bannerFile, err := c.FormFile("banner")
+	if err == nil {
+		bannerPath, err := helpers.UploadFile(c, bannerFile, "event_banners")
+		if err != nil {
+			helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		series.BannerPath = bannerPath
+	}
+
+	occurrences := rule.Occurrences(startTime, maxSeriesOccurrences)
+	duration := endTime.Sub(startTime)
+
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&series).Error; err != nil {
+			return err
+		}
+
+		for _, occurrence := range occurrences {
+			event := models.Event{
+				ID:          uuid.New(),
+				Title:       series.Title,
+				Description: series.Description,
+				StartTime:   occurrence,
+				EndTime:     occurrence.Add(duration),
+				Province:    series.Province,
+				City:        series.City,
+				District:    series.District,
+				SubDistrict: series.SubDistrict,
+				Location:    series.Location,
+				Latitude:    series.Latitude,
+				Longitude:   series.Longitude,
+				VenueID:     series.VenueID,
+				SeriesID:    &series.ID,
+				Status:      models.EventStatusDraft,
+				UserID:      series.UserID,
+				Categories:  series.Categories,
+				BannerPath:  series.BannerPath,
+			}
+			for _, template := range series.TicketTemplates {
+				event.Tickets = append(event.Tickets, models.Ticket{
+					ID:                     uuid.New(),
+					Type:                   template.Type,
+					Price:                  template.Price,
+					Limit:                  template.Limit,
+					EntryPolicy:            template.EntryPolicy,
+					MaxEntries:             template.MaxEntries,
+					RequireAttendeeDetails: template.RequireAttendeeDetails,
+					MinPerOrder:            template.MinPerOrder,
+					MaxPerOrder:            template.MaxPerOrder,
+					MaxPerUser:             template.MaxPerUser,
+					RequireVerifiedPhone:   template.RequireVerifiedPhone,
+					OnePerPhone:            template.OnePerPhone,
+				})
+			}
+
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+			if series.VenueID != nil {
+				if err := copyVenueSeatMap(tx, *series.VenueID, event.ID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create event series.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Event series created successfully.",
+		"series_id":   series.ID,
+		"occurrences": len(occurrences),
+	})
+}
+
+func GetSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var series models.EventSeries
+	err := gormDB.Preload("Categories").Preload("TicketTemplates").Preload("User").Where("id = ?", seriesID).First(&series).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusNotFound, "Event series not found.")
+			return
+		}
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving event series.")
+		return
+	}
+
+	var occurrences []models.Event
+	err = gormDB.Preload("Tickets", "visibility <> ?", models.TicketVisibilityHidden).
+		Where("series_id = ? AND end_time >= ? AND status IN ?", series.ID, time.Now(), publicEventStatuses).
+		Order("start_time").Find(&occurrences).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving occurrences.")
+		return
+	}
+
+	upcoming := make([]gin.H, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		upcoming = append(upcoming, gin.H{
+			"event_id":     occurrence.ID,
+			"title":        occurrence.Title,
+			"start_time":   occurrence.StartTime,
+			"end_time":     occurrence.EndTime,
+			"location":     occurrence.Location,
+			"status":       occurrence.Status,
+			"cancelled_at": occurrence.CancelledAt,
+			"tickets":      occurrence.Tickets,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series":   series,
+		"upcoming": upcoming,
+	})
+}
+
+func CancelSeriesOccurrence(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var event models.Event
+	err := gormDB.Where("id = ? AND series_id = ? AND user_id = ?", c.Param("eventId"), c.Param("id"), userID).First(&event).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusForbidden, "Occurrence not found or you don't have permission to cancel it.")
+		return
+	}
+
+	if msg := cancelEvent(gormDB, &event); msg != "" {
+		helpers.RespondWithError(c, http.StatusConflict, msg)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Occurrence cancelled successfully.",
+		"status":       event.Status,
+		"cancelled_at": event.CancelledAt,
+	})
+}
+
+// PublishSeries publishes, or schedules with publish_at, every upcoming draft
+// occurrence of a series.
+func PublishSeries(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var series models.EventSeries
+	if err := gormDB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&series).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusForbidden, "Event series not found or you don't have permission to publish it.")
+		return
+	}
+
+	publishAt, ok := parsePublishAt(c)
+	if !ok {
+		return
+	}
+
+	query := gormDB.Model(&models.Event{}).Where("series_id = ? AND status = ? AND end_time > ?", series.ID, models.EventStatusDraft, time.Now())
+	if publishAt != nil {
+		query = query.Where("end_time > ?", *publishAt)
+	}
+
+	result := query.Updates(publishUpdates(publishAt))
+	if result.Error != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to publish event series.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Event series published successfully.",
+		"occurrences": result.RowsAffected,
+	})
+}
+
+// bannerInUse reports whether another event or a series still points at the
+// banner file, as occurrences of a series share one upload.
+func bannerInUse(gormDB *gorm.DB, path string, eventID uuid.UUID) bool {
+	var events, series int64
+	gormDB.Model(&models.Event{}).Where("banner_path = ? AND id <> ?", path, eventID).Count(&events)
+	gormDB.Model(&models.EventSeries{}).Where("banner_path = ?", path).Count(&series)
+	return events > 0 || series > 0
+}
@@ -1,20 +1,220 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/farellandr/spoticket/internal/helpers"
 	"github.com/farellandr/spoticket/internal/models"
+	"github.com/farellandr/spoticket/internal/waitlist"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type TicketRequest struct {
-	Type    string    `json:"type" binding:"required"`
-	Price   int       `json:"price" binding:"required"`
-	Limit   int       `json:"limit"`
-	EventID uuid.UUID `json:"event_id" binding:"required"`
+	Type                   string     `json:"type" binding:"required"`
+	Price                  int        `json:"price" binding:"required"`
+	Limit                  int        `json:"limit"`
+	EntryPolicy            string     `json:"entry_policy"`
+	MaxEntries             int        `json:"max_entries"`
+	RequireAttendeeDetails bool       `json:"require_attendee_details"`
+	Visibility             string     `json:"visibility"`
+	MinPerOrder            int        `json:"min_per_order"`
+	MaxPerOrder            int        `json:"max_per_order"`
+	MaxPerUser             int        `json:"max_per_user"`
+	RequireVerifiedPhone   bool       `json:"require_verified_phone"`
+	OnePerPhone            bool       `json:"one_per_phone"`
+	SalesStart             *time.Time `json:"sales_start"`
+	SalesEnd               *time.Time `json:"sales_end"`
+	OpensAfterTicketID     *uuid.UUID `json:"opens_after_ticket_id"`
+	EventID                uuid.UUID  `json:"event_id" binding:"required"`
+}
+
+func validateEntryPolicy(req *TicketRequest) string {
+	switch req.EntryPolicy {
+	case "":
+		req.EntryPolicy = models.EntryPolicySingle
+		req.MaxEntries = 0
+	case models.EntryPolicySingle, models.EntryPolicyDaily, models.EntryPolicyUnlimited:
+		req.MaxEntries = 0
+	case models.EntryPolicyMulti:
+		if req.MaxEntries < 2 {
+			return "Max entries must be at least 2 for multi-entry tickets."
+		}
+	default:
+		return "Invalid entry policy."
+	}
+	return ""
+}
+
+// validatePurchaseLimits checks the per-order and per-user quantity rules. A
+// zero limit means no limit.
+func validatePurchaseLimits(req *TicketRequest) string {
+	if req.MinPerOrder < 0 || req.MaxPerOrder < 0 || req.MaxPerUser < 0 {
+		return "Purchase limits must not be negative."
+	}
+	if req.MaxPerOrder > 0 && req.MinPerOrder > req.MaxPerOrder {
+		return "Min per order must not exceed max per order."
+	}
+	if req.MaxPerUser > 0 && req.MinPerOrder > req.MaxPerUser {
+		return "Min per order must not exceed max per user."
+	}
+	if req.Limit > 0 && req.MinPerOrder > req.Limit {
+		return "Min per order must not exceed the ticket limit."
+	}
+	return ""
+}
+
+func venueCapacityError(gormDB *gorm.DB, event *models.Event, ticketID *uuid.UUID, limit int) string {
+	fits, capacity, err := checkVenueCapacity(gormDB, event, ticketID, limit)
+	if err != nil {
+		return "Error checking venue capacity."
+	}
+	if !fits {
+		return fmt.Sprintf("Ticket limits exceed the venue capacity of %d.", capacity)
+	}
+	return ""
+}
+
+// ticketSalesEnd is the end of a tier's sales window. Without an explicit end
+// sales close when the event starts.
+func ticketSalesEnd(ticket *models.Ticket, event *models.Event) time.Time {
+	if ticket.SalesEnd != nil {
+		return *ticket.SalesEnd
+	}
+	return event.StartTime
+}
+
+func validateSalesWindow(gormDB *gorm.DB, req *TicketRequest, event *models.Event, ticketID *uuid.UUID) string {
+	salesEnd := event.StartTime
+	if req.SalesEnd != nil {
+		if req.SalesEnd.After(event.EndTime) {
+			return "Sales end must not be after the event ends."
+		}
+		salesEnd = *req.SalesEnd
+	}
+	if req.SalesStart != nil && !salesEnd.After(*req.SalesStart) {
+		return "Sales end must be after sales start."
+	}
+
+	if req.OpensAfterTicketID == nil {
+		return ""
+	}
+	if ticketID != nil && *req.OpensAfterTicketID == *ticketID {
+		return "A ticket can't open after itself."
+	}
+
+	var tickets []models.Ticket
+	if err := gormDB.Where("event_id = ?", event.ID).Find(&tickets).Error; err != nil {
+		return "Error checking previous tier."
+	}
+	previous := make(map[uuid.UUID]*uuid.UUID, len(tickets))
+	for _, ticket := range tickets {
+		previous[ticket.ID] = ticket.OpensAfterTicketID
+	}
+	if _, ok := previous[*req.OpensAfterTicketID]; !ok {
+		return "Previous tier must be a ticket of the same event."
+	}
+
+	// Walk the chain of previous tiers to reject cycles.
+	if ticketID != nil {
+		for id := req.OpensAfterTicketID; id != nil; id = previous[*id] {
+			if *id == *ticketID {
+				return "Tiers can't open after each other in a cycle."
+			}
+		}
+	}
+	return ""
+}
+
+// ticketSaleStatus reports whether a tier is on sale. Tickets must have their
+// purchases and pending quantities loaded; tiers maps the event's tickets by
+// ID so a tier that opens after a previous one stays upcoming until that tier
+// sells out or closes.
+func ticketSaleStatus(ticket *models.Ticket, event *models.Event, tiers map[uuid.UUID]*models.Ticket, now time.Time) string {
+	return tierSaleStatus(ticket, event, tiers, now, map[uuid.UUID]bool{})
+}
+
+// tierSaleStatus follows the chain of tiers a ticket opens after. A tier seen
+// twice means the chain loops, which is treated as having no previous tier.
+func tierSaleStatus(ticket *models.Ticket, event *models.Event, tiers map[uuid.UUID]*models.Ticket, now time.Time, visited map[uuid.UUID]bool) string {
+	visited[ticket.ID] = true
+
+	if len(ticket.Purchases)+ticket.PendingQuantity >= ticket.Limit {
+		return models.TicketSaleSoldOut
+	}
+	if !now.Before(ticketSalesEnd(ticket, event)) {
+		return models.TicketSaleEnded
+	}
+	if ticket.SalesStart != nil && now.Before(*ticket.SalesStart) {
+		return models.TicketSaleUpcoming
+	}
+
+	if ticket.OpensAfterTicketID != nil && !visited[*ticket.OpensAfterTicketID] {
+		if previous, ok := tiers[*ticket.OpensAfterTicketID]; ok {
+			switch tierSaleStatus(previous, event, tiers, now, visited) {
+			case models.TicketSaleUpcoming, models.TicketSaleOnSale:
+				return models.TicketSaleUpcoming
+			}
+		}
+	}
+	return models.TicketSaleOnSale
+}
+
+// loadPendingQuantities fills PendingQuantity with the tickets held by unpaid
+// checkouts. Resale checkouts move existing tickets and aren't counted.
+func loadPendingQuantities(gormDB *gorm.DB, tickets []models.Ticket) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	ticketIDs := make([]uuid.UUID, 0, len(tickets))
+	for _, ticket := range tickets {
+		ticketIDs = append(ticketIDs, ticket.ID)
+	}
+
+	var rows []struct {
+		TicketID uuid.UUID
+		Pending  int
+	}
+	err := gormDB.Model(&models.Payment{}).
+		Select("ticket_id, COALESCE(SUM(quantity), 0) AS pending").
+		Where("ticket_id IN ? AND status = ? AND transaction_id NOT LIKE ?", ticketIDs, "PENDING", "RSL-%").
+		Group("ticket_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		pending[row.TicketID] = row.Pending
+	}
+	for i := range tickets {
+		tickets[i].PendingQuantity = pending[tickets[i].ID]
+	}
+	return nil
+}
+
+// applyTicketSaleStatuses fills SaleStatus on the event's tickets, which must
+// be loaded with their purchases.
+func applyTicketSaleStatuses(gormDB *gorm.DB, event *models.Event) error {
+	if err := loadPendingQuantities(gormDB, event.Tickets); err != nil {
+		return err
+	}
+
+	tiers := make(map[uuid.UUID]*models.Ticket, len(event.Tickets))
+	for i := range event.Tickets {
+		tiers[event.Tickets[i].ID] = &event.Tickets[i]
+	}
+
+	now := time.Now()
+	for i := range event.Tickets {
+		event.Tickets[i].SaleStatus = ticketSaleStatus(&event.Tickets[i], event, tiers, now)
+	}
+	return nil
 }
 
 func CreateTicket(c *gin.Context) {
@@ -29,6 +229,21 @@ func CreateTicket(c *gin.Context) {
 		return
 	}
 
+	if msg := validateEntryPolicy(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if msg := validatePurchaseLimits(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if msg := validateVisibility(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
@@ -52,12 +267,34 @@ func CreateTicket(c *gin.Context) {
 		return
 	}
 
+	if msg := venueCapacityError(gormDB, &event, nil, req.Limit); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if msg := validateSalesWindow(gormDB, &req, &event, nil); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
 	ticket := models.Ticket{
-		ID:      uuid.New(),
-		Type:    req.Type,
-		Price:   req.Price,
-		Limit:   req.Limit,
-		EventID: req.EventID,
+		ID:                     uuid.New(),
+		Type:                   req.Type,
+		Price:                  req.Price,
+		Limit:                  req.Limit,
+		EntryPolicy:            req.EntryPolicy,
+		MaxEntries:             req.MaxEntries,
+		EventID:                req.EventID,
+		RequireAttendeeDetails: req.RequireAttendeeDetails,
+		Visibility:             req.Visibility,
+		MinPerOrder:            req.MinPerOrder,
+		MaxPerOrder:            req.MaxPerOrder,
+		MaxPerUser:             req.MaxPerUser,
+		RequireVerifiedPhone:   req.RequireVerifiedPhone,
+		OnePerPhone:            req.OnePerPhone,
+		SalesStart:             req.SalesStart,
+		SalesEnd:               req.SalesEnd,
+		OpensAfterTicketID:     req.OpensAfterTicketID,
 	}
 
 	if err := gormDB.Create(&ticket).Error; err != nil {
@@ -91,6 +328,11 @@ func GetTicket(c *gin.Context) {
 		return
 	}
 
+	if ticket.Visibility == models.TicketVisibilityHidden && !unlockedTicketIDs(gormDB, ticket.EventID, c.Query("access_code"))[ticket.ID] {
+		helpers.RespondWithError(c, http.StatusNotFound, "Ticket not found.")
+		return
+	}
+
 	c.JSON(http.StatusOK, ticket)
 }
 
@@ -107,6 +349,21 @@ func UpdateTicket(c *gin.Context) {
 		return
 	}
 
+	if msg := validateEntryPolicy(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if msg := validatePurchaseLimits(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if msg := validateVisibility(&req); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
@@ -132,15 +389,41 @@ func UpdateTicket(c *gin.Context) {
 		return
 	}
 
+	if msg := venueCapacityError(gormDB, &event, &ticket.ID, req.Limit); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if msg := validateSalesWindow(gormDB, &req, &event, &ticket.ID); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
 	ticket.Type = req.Type
 	ticket.Price = req.Price
 	ticket.Limit = req.Limit
+	ticket.EntryPolicy = req.EntryPolicy
+	ticket.MaxEntries = req.MaxEntries
+	ticket.RequireAttendeeDetails = req.RequireAttendeeDetails
+	ticket.Visibility = req.Visibility
+	ticket.MinPerOrder = req.MinPerOrder
+	ticket.MaxPerOrder = req.MaxPerOrder
+	ticket.MaxPerUser = req.MaxPerUser
+	ticket.RequireVerifiedPhone = req.RequireVerifiedPhone
+	ticket.OnePerPhone = req.OnePerPhone
+	ticket.SalesStart = req.SalesStart
+	ticket.SalesEnd = req.SalesEnd
+	ticket.OpensAfterTicketID = req.OpensAfterTicketID
 
 	if err := gormDB.Save(&ticket).Error; err != nil {
 		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update ticket.")
 		return
 	}
 
+	if err := waitlist.OfferFreedTickets(gormDB, ticket.ID); err != nil {
+		fmt.Printf("Error offering waitlisted tickets: %v\n", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Ticket updated successfully.",
 		"ticket":  ticket,
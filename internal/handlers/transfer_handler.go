@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TransferRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// transferParty is the part of a user the other side of a transfer sees.
+type transferParty struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+}
+
+type transferResponse struct {
+	models.Transfer
+	FromUser *transferParty
+	ToUser   *transferParty
+}
+
+func newTransferParty(user *models.User) *transferParty {
+	if user == nil {
+		return nil
+	}
+	return &transferParty{ID: user.ID, Name: user.Name, Email: user.Email}
+}
+
+func transferResponses(transfers []models.Transfer) []transferResponse {
+	responses := make([]transferResponse, len(transfers))
+	for i, transfer := range transfers {
+		responses[i] = transferResponse{
+			Transfer: transfer,
+			FromUser: newTransferParty(transfer.FromUser),
+			ToUser:   newTransferParty(transfer.ToUser),
+		}
+	}
+	return responses
+}
+
+var errTransferUnavailable = errors.New("transfer is no longer available")
+
+// ownershipBlockReason explains why a purchase can't change hands, or returns
+// an empty string when it can. The purchase must have Payment and
+// Ticket.Event loaded.
+func ownershipBlockReason(purchase *models.Purchase) string {
+	if purchase.Payment == nil || purchase.Payment.Status != "PAID" {
+		return "Payment is not paid."
+	}
+	if purchase.IsUsed || purchase.EntryCount > 0 {
+		return "Ticket has already been used."
+	}
+	if time.Now().After(purchase.Ticket.Event.EndTime) {
+		return "Ticket expired."
+	}
+	return ""
+}
+
+func transferBlockReason(purchase *models.Purchase) string {
+	if purchase.Ticket.Event.TransfersDisabled {
+		return "Transfers are disabled for this event."
+	}
+	return ownershipBlockReason(purchase)
+}
+
+func InitiateTransfer(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	purchaseID, err := uuid.Parse(c.Param("purchaseId"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid purchase ID.")
+		return
+	}
+
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var purchase models.Purchase
+	if err := gormDB.Preload("Ticket.Event").Preload("Payment").Preload("User").First(&purchase, purchaseID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found.")
+		return
+	}
+
+	if purchase.UserID != userID {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to transfer this ticket.")
+		return
+	}
+
+	if reason := transferBlockReason(&purchase); reason != "" {
+		helpers.RespondWithError(c, http.StatusForbidden, reason)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == strings.ToLower(purchase.User.Email) {
+		helpers.RespondWithError(c, http.StatusBadRequest, "You can't transfer a ticket to yourself.")
+		return
+	}
+
+	// The purchase row is locked while checking for open transfers and
+	// listings, as CreateResaleListing does, so two requests can't both open
+	// one for the same ticket.
+	var transfer models.Transfer
+	var blockReason string
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Purchase
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, purchase.ID).Error; err != nil {
+			return err
+		}
+		if locked.UserID != purchase.UserID {
+			blockReason = "You don't have permission to transfer this ticket."
+			return nil
+		}
+
+		var openCount int64
+		tx.Model(&models.Transfer{}).Where("purchase_id = ? AND status = ?", purchase.ID, models.TransferStatusPending).Count(&openCount)
+		if openCount > 0 {
+			blockReason = "This ticket already has a pending transfer."
+			return nil
+		}
+		tx.Model(&models.ResaleListing{}).Where("purchase_id = ? AND status = ?", purchase.ID, models.ResaleStatusListed).Count(&openCount)
+		if openCount > 0 {
+			blockReason = "This ticket is listed for resale."
+			return nil
+		}
+
+		transfer = models.Transfer{
+			ID:         uuid.New(),
+			PurchaseID: purchase.ID,
+			FromUserID: purchase.UserID,
+			ToEmail:    email,
+			Status:     models.TransferStatusPending,
+		}
+		return tx.Create(&transfer).Error
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create transfer.")
+		return
+	}
+	if blockReason != "" {
+		helpers.RespondWithError(c, http.StatusConflict, blockReason)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Transfer initiated successfully.",
+		"transfer_id": transfer.ID,
+	})
+}
+
+func ListTransfers(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var user models.User
+	if err := gormDB.Where("id = ?", userID).First(&user).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	var incoming []models.Transfer
+	if err := gormDB.Preload("Purchase.Ticket.Event").Preload("FromUser").
+		Where("to_email = ? OR to_user_id = ?", strings.ToLower(user.Email), user.ID).
+		Order("created_at DESC").Find(&incoming).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving transfers.")
+		return
+	}
+
+	var outgoing []models.Transfer
+	if err := gormDB.Preload("Purchase.Ticket.Event").Preload("ToUser").
+		Where("from_user_id = ?", user.ID).
+		Order("created_at DESC").Find(&outgoing).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving transfers.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"incoming": transferResponses(incoming),
+		"outgoing": transferResponses(outgoing),
+	})
+}
+
+func ListPurchaseTransfers(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	purchaseID, err := uuid.Parse(c.Param("purchaseId"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid purchase ID.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var purchase models.Purchase
+	if err := gormDB.Preload("Ticket.Event").First(&purchase, purchaseID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Purchase not found.")
+		return
+	}
+
+	var transfers []models.Transfer
+	if err := gormDB.Preload("FromUser").Preload("ToUser").
+		Where("purchase_id = ?", purchase.ID).
+		Order("created_at ASC").Find(&transfers).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving transfers.")
+		return
+	}
+
+	allowed := purchase.UserID == userID || purchase.Ticket.Event.UserID == userID
+	for _, transfer := range transfers {
+		if transfer.FromUserID == userID || (transfer.ToUserID != nil && *transfer.ToUserID == userID) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to view this ticket's transfers.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"purchase_id": purchase.ID,
+		"transfers":   transferResponses(transfers),
+	})
+}
+
+func AcceptTransfer(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var user models.User
+	if err := gormDB.Where("id = ?", userID).First(&user).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	var transfer models.Transfer
+	if err := gormDB.Where("id = ?", c.Param("id")).First(&transfer).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Transfer not found.")
+		return
+	}
+
+	if transfer.ToEmail != strings.ToLower(user.Email) {
+		helpers.RespondWithError(c, http.StatusForbidden, "This transfer is not addressed to you.")
+		return
+	}
+
+	var blockReason string
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, transfer.ID).Error; err != nil {
+			return err
+		}
+		if transfer.Status != models.TransferStatusPending {
+			return errTransferUnavailable
+		}
+
+		var purchase models.Purchase
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&purchase, transfer.PurchaseID).Error; err != nil {
+			return err
+		}
+		if purchase.UserID != transfer.FromUserID {
+			return errTransferUnavailable
+		}
+
+		if err := tx.Preload("Ticket.Event").Preload("Payment").First(&purchase, purchase.ID).Error; err != nil {
+			return err
+		}
+		if blockReason = transferBlockReason(&purchase); blockReason != "" {
+			return nil
+		}
+
+		if err := tx.Model(&purchase).Update("user_id", user.ID).Error; err != nil {
+			return err
+		}
+
+		// The previous owner's attendee details don't belong to the new
+		// holder, as on resale.
+		if err := tx.Where("purchase_id = ?", purchase.ID).Delete(&models.Attendee{}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&transfer).Updates(map[string]interface{}{
+			"status":       models.TransferStatusAccepted,
+			"to_user_id":   user.ID,
+			"responded_at": now,
+		}).Error
+	})
+	if errors.Is(err, errTransferUnavailable) {
+		helpers.RespondWithError(c, http.StatusConflict, "This transfer is no longer available.")
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to accept transfer.")
+		return
+	}
+	if blockReason != "" {
+		helpers.RespondWithError(c, http.StatusForbidden, blockReason)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Transfer accepted successfully.",
+		"purchase_id": transfer.PurchaseID,
+	})
+}
+
+func DeclineTransfer(c *gin.Context) {
+	respondToTransfer(c, models.TransferStatusDeclined)
+}
+
+func CancelTransfer(c *gin.Context) {
+	respondToTransfer(c, models.TransferStatusCancelled)
+}
+
+// respondToTransfer closes a pending transfer without moving the ticket. Only
+// the recipient may decline and only the sender may cancel.
+func respondToTransfer(c *gin.Context, status string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var user models.User
+	if err := gormDB.Where("id = ?", userID).First(&user).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	var transfer models.Transfer
+	if err := gormDB.Where("id = ?", c.Param("id")).First(&transfer).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Transfer not found.")
+		return
+	}
+
+	if status == models.TransferStatusDeclined && transfer.ToEmail != strings.ToLower(user.Email) {
+		helpers.RespondWithError(c, http.StatusForbidden, "This transfer is not addressed to you.")
+		return
+	}
+	if status == models.TransferStatusCancelled && transfer.FromUserID != user.ID {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to cancel this transfer.")
+		return
+	}
+
+	updates := map[string]interface{}{
+		"status":       status,
+		"responded_at": time.Now(),
+	}
+	if status == models.TransferStatusDeclined {
+		updates["to_user_id"] = user.ID
+	}
+
+	result := gormDB.Model(&models.Transfer{}).
+		Where("id = ? AND status = ?", transfer.ID, models.TransferStatusPending).
+		Updates(updates)
+	if result.Error != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update transfer.")
+		return
+	}
+	if result.RowsAffected == 0 {
+		helpers.RespondWithError(c, http.StatusConflict, "This transfer is no longer available.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Transfer " + status + " successfully.",
+	})
+}
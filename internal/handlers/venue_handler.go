@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func parseCoordinate(value string, limit float64) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil || coordinate < -limit || coordinate > limit {
+		return nil, fmt.Errorf("invalid coordinate")
+	}
+	return &coordinate, nil
+}
+
+func bindVenueForm(c *gin.Context, venue *models.Venue) string {
+	venue.Name = c.PostForm("name")
+	venue.Address = c.PostForm("address")
+	venue.Province = c.PostForm("province")
+	venue.City = c.PostForm("city")
+	venue.District = c.PostForm("district")
+	venue.SubDistrict = c.PostForm("sub_district")
+	venue.WheelchairAccessible = c.PostForm("wheelchair_accessible") == "true"
+	venue.AccessibilityNotes = c.PostForm("accessibility_notes")
+
+	if venue.Name == "" || venue.Address == "" || venue.City == "" || venue.Province == "" {
+		return "Missing required fields."
+	}
+
+	var err error
+	if venue.Latitude, err = parseCoordinate(c.PostForm("latitude"), 90); err != nil {
+		return "Invalid latitude."
+	}
+	if venue.Longitude, err = parseCoordinate(c.PostForm("longitude"), 180); err != nil {
+		return "Invalid longitude."
+	}
+	if (venue.Latitude == nil) != (venue.Longitude == nil) {
+		return "Latitude and longitude must be provided together."
+	}
+
+	venue.Capacity = 0
+	if capacityStr := c.PostForm("capacity"); capacityStr != "" {
+		venue.Capacity, err = helpers.StringToInt(capacityStr)
+		if err != nil || venue.Capacity < 0 {
+			return "Invalid capacity."
+		}
+	}
+
+	return ""
+}
+
+func uploadVenuePhotos(c *gin.Context, venueID uuid.UUID) ([]models.VenuePhoto, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil
+	}
+
+	var photos []models.VenuePhoto
+	for _, fileHeader := range form.File["photos"] {
+		path, err := helpers.UploadFile(c, fileHeader, "venue_photos")
+		if err != nil {
+			for _, photo := range photos {
+				helpers.DeleteFile(photo.Path)
+			}
+			return nil, err
+		}
+		photos = append(photos, models.VenuePhoto{VenueID: venueID, Path: path})
+	}
+	return photos, nil
+}
+
+// findManagedVenue loads a venue the current user may edit: its creator or an
+// admin.
+func findManagedVenue(c *gin.Context, gormDB *gorm.DB) (*models.Venue, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return nil, false
+	}
+
+	var user models.User
+	if err := gormDB.Preload("Role").First(&user, "id = ?", userID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return nil, false
+	}
+
+	var venue models.Venue
+	if err := gormDB.Where("id = ?", c.Param("id")).First(&venue).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Venue not found.")
+		return nil, false
+	}
+
+	if venue.UserID != user.ID && user.Role.Name != "admin" {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to modify this venue.")
+		return nil, false
+	}
+
+	return &venue, true
+}
+
+func CreateVenue(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var user models.User
+	if err := gormDB.Where("id = ?", userID).Preload("Role").First(&user).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+
+	if user.Role.Name != "organizer" && user.Role.Name != "admin" {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "You have no permission to create a venue.")
+		return
+	}
+
+	venue := models.Venue{
+		ID:     uuid.New(),
+		UserID: user.ID,
+	}
+	if msg := bindVenueForm(c, &venue); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	photos, err := uploadVenuePhotos(c, venue.ID)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	venue.Photos = photos
+
+	if err := gormDB.Create(&venue).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to create venue.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Venue created successfully.",
+		"venue_id": venue.ID,
+	})
+}
+
+func ListVenues(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	pagination, err := helpers.ParsePagination(c)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := gormDB.Model(&models.Venue{})
+	if name := c.Query("name"); name != "" {
+		query = query.Where("name ILIKE ?", "%"+name+"%")
+	}
+	if province := c.Query("province"); province != "" {
+		query = query.Where("province = ?", province)
+	}
+	if city := c.Query("city"); city != "" {
+		query = query.Where("city = ?", city)
+	}
+	if c.Query("wheelchair_accessible") == "true" {
+		query = query.Where("wheelchair_accessible = ?", true)
+	}
+
+	result, err := pagination.Fetch(query, "venues.id", []helpers.SortKey{{Column: "venues.name"}}, nil)
+	if err != nil {
+		helpers.RespondWithPageError(c, err, "Error retrieving venues.")
+		return
+	}
+
+	var venues []models.Venue
+	if err := gormDB.Preload("Photos").Where("id IN ?", result.IDs).Find(&venues).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving venues.")
+		return
+	}
+
+	response := pagination.Response(result)
+	response["venues"] = helpers.SortByIDs(venues, result.IDs, func(venue *models.Venue) uuid.UUID { return venue.ID })
+	c.JSON(http.StatusOK, response)
+}
+
+func GetVenue(c *gin.Context) {
+	venueID := c.Param("id")
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var venue models.Venue
+	err := gormDB.Preload("Photos").Preload("SeatSections.Seats", func(db *gorm.DB) *gorm.DB {
+		return db.Order("row, number")
+	}).Where("id = ?", venueID).First(&venue).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			helpers.RespondWithError(c, http.StatusNotFound, "Venue not found.")
+			return
+		}
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving venue.")
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+func UpdateVenue(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	venue, ok := findManagedVenue(c, gormDB)
+	if !ok {
+		return
+	}
+
+	if msg := bindVenueForm(c, venue); msg != "" {
+		helpers.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	photos, err := uploadVenuePhotos(c, venue.ID)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(venue).Error; err != nil {
+			return err
+		}
+		if len(photos) > 0 {
+			return tx.Create(&photos).Error
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to update venue.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Venue updated successfully.",
+		"venue":   venue,
+	})
+}
+
+func DeleteVenue(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	venue, ok := findManagedVenue(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var upcomingEvents int64
+	gormDB.Model(&models.Event{}).Where("venue_id = ? AND end_time > ?", venue.ID, time.Now()).Count(&upcomingEvents)
+	if upcomingEvents > 0 {
+		helpers.RespondWithError(c, http.StatusConflict, "Venue still has upcoming events.")
+		return
+	}
+
+	if err := gormDB.Delete(venue).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to delete venue.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Venue deleted successfully.",
+	})
+}
+
+func StreamVenuePhoto(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var photo models.VenuePhoto
+	if err := gormDB.Where("id = ? AND venue_id = ?", c.Param("photoId"), c.Param("id")).First(&photo).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Photo not found.")
+		return
+	}
+
+	c.File(photo.Path)
+}
+
+func DeleteVenuePhoto(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	venue, ok := findManagedVenue(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var photo models.VenuePhoto
+	if err := gormDB.Where("id = ? AND venue_id = ?", c.Param("photoId"), venue.ID).First(&photo).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Photo not found.")
+		return
+	}
+
+	if err := gormDB.Delete(&photo).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to delete photo.")
+		return
+	}
+
+	if err := helpers.DeleteFile(photo.Path); err != nil {
+		fmt.Printf("Error deleting venue photo: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Photo deleted successfully.",
+	})
+}
+
+func SaveVenueSeatMap(c *gin.Context) {
+	var req SeatMapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	venue, ok := findManagedVenue(c, gormDB)
+	if !ok {
+		return
+	}
+
+	sections, err := buildSeatSections(req)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	for i := range sections {
+		sections[i].VenueID = &venue.ID
+	}
+
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("venue_id = ?", venue.ID).Delete(&models.SeatSection{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&sections).Error
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to save seat map.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Seat map saved successfully.",
+		"sections": len(sections),
+	})
+}
+
+// copyVenueSeatMap gives an event its own copy of the venue's seat map so that
+// tier assignments and holds never touch the venue template.
+func copyVenueSeatMap(tx *gorm.DB, venueID, eventID uuid.UUID) error {
+	var templates []models.SeatSection
+	if err := tx.Preload("Seats").Where("venue_id = ?", venueID).Find(&templates).Error; err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+
+	sections := make([]models.SeatSection, 0, len(templates))
+	for _, template := range templates {
+		section := models.SeatSection{EventID: &eventID, Name: template.Name}
+		for _, seat := range template.Seats {
+			section.Seats = append(section.Seats, models.Seat{
+				Row:          seat.Row,
+				Number:       seat.Number,
+				IsAccessible: seat.IsAccessible,
+			})
+		}
+		sections = append(sections, section)
+	}
+	return tx.Create(&sections).Error
+}
+
+// checkVenueCapacity reports whether the event's ticket limits fit the venue
+// once ticketID (if any) has the given limit.
+func checkVenueCapacity(gormDB *gorm.DB, event *models.Event, ticketID *uuid.UUID, limit int) (bool, int, error) {
+	if event.VenueID == nil {
+		return true, 0, nil
+	}
+
+	var venue models.Venue
+	if err := gormDB.First(&venue, "id = ?", *event.VenueID).Error; err != nil {
+		return false, 0, err
+	}
+	if venue.Capacity == 0 {
+		return true, 0, nil
+	}
+
+	query := gormDB.Model(&models.Ticket{}).Where("event_id = ?", event.ID)
+	if ticketID != nil {
+		query = query.Where("id <> ?", *ticketID)
+	}
+
+	var allocated int64
+	if err := query.Select("COALESCE(SUM(\"limit\"), 0)").Scan(&allocated).Error; err != nil {
+		return false, 0, err
+	}
+
+	return int(allocated)+limit <= venue.Capacity, venue.Capacity, nil
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func generateVoucherQRData(voucher *models.AddonVoucher) string {
+	secretKey := os.Getenv("JWT_SECRET")
+	signature := generateSignature(voucher.ID, voucher.PaymentID, voucher.UserID, secretKey)
+	return fmt.Sprintf("voucher:%s;product:%s;event:%s;signature:%s",
+		voucher.ID.String(),
+		voucher.ProductVariant.ProductID.String(),
+		voucher.ProductVariant.Product.EventID.String(),
+		signature,
+	)
+}
+
+func validateVoucherQRSignature(voucher *models.AddonVoucher, qrData string) bool {
+	fields, err := parseQRData(qrData, "voucher")
+	if err != nil {
+		return false
+	}
+
+	secretKey := os.Getenv("JWT_SECRET")
+	expectedSignature := generateSignature(voucher.ID, voucher.PaymentID, voucher.UserID, secretKey)
+	return hmac.Equal([]byte(expectedSignature), []byte(fields["signature"]))
+}
+
+func voucherDetails(voucher *models.AddonVoucher) gin.H {
+	product := voucher.ProductVariant.Product
+	return gin.H{
+		"id":           voucher.ID,
+		"event_id":     product.EventID,
+		"event_title":  product.Event.Title,
+		"product_type": product.Type,
+		"product":      product.Name,
+		"variant":      voucher.ProductVariant.Name,
+		"is_used":      voucher.IsUsed,
+		"used_at":      voucher.UsedAt,
+	}
+}
+
+func ListMyVouchers(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var vouchers []models.AddonVoucher
+	err := gormDB.Preload("ProductVariant.Product.Event").
+		Where("user_id = ?", userID).Order("created_at DESC").Find(&vouchers).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving vouchers.")
+		return
+	}
+
+	response := make([]gin.H, 0, len(vouchers))
+	for i := range vouchers {
+		response = append(response, voucherDetails(&vouchers[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"vouchers": response,
+	})
+}
+
+func GenerateVoucherQR(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User not authenticated.")
+		return
+	}
+
+	voucherID, err := uuid.Parse(c.Param("voucherId"))
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid voucher ID")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var voucher models.AddonVoucher
+	if err := gormDB.Preload("ProductVariant.Product.Event").First(&voucher, voucherID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Voucher not found")
+		return
+	}
+
+	if voucher.UserID != userID {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to generate QR code for this voucher")
+		return
+	}
+
+	if time.Now().After(voucher.ProductVariant.Product.Event.EndTime) {
+		helpers.RespondWithError(c, http.StatusForbidden, "Voucher expired")
+		return
+	}
+
+	if voucher.IsUsed {
+		helpers.RespondWithError(c, http.StatusForbidden, "Voucher already redeemed")
+		return
+	}
+
+	qrImage, err := qrcode.Encode(generateVoucherQRData(&voucher), qrcode.Medium, 256)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to generate QR code")
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", qrImage)
+}
+
+func ValidateVoucher(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User not authenticated.")
+		return
+	}
+	staffID, ok := userID.(uuid.UUID)
+	if !ok {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID type.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var validationRequest struct {
+		QRData string `json:"qr_data" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&validationRequest); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	fields, err := parseQRData(validationRequest.QRData, "voucher")
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid QR code format")
+		return
+	}
+	voucherID, err := uuid.Parse(fields["voucher"])
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid QR code format")
+		return
+	}
+
+	var voucher models.AddonVoucher
+	if err := gormDB.Preload("ProductVariant.Product.Event").First(&voucher, voucherID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Voucher not found")
+		return
+	}
+
+	if !validateVoucherQRSignature(&voucher, validationRequest.QRData) {
+		helpers.RespondWithError(c, http.StatusForbidden, "Invalid QR code signature")
+		return
+	}
+
+	if voucher.ProductVariant.Product.Event.UserID != staffID {
+		helpers.RespondWithError(c, http.StatusForbidden, "You don't have permission to validate this voucher")
+		return
+	}
+
+	var denial string
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		var locked models.AddonVoucher
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, voucher.ID).Error; err != nil {
+			return err
+		}
+		if locked.IsUsed {
+			denial = "Voucher already redeemed"
+			return nil
+		}
+
+		now := time.Now()
+		voucher.IsUsed = true
+		voucher.UsedAt = &now
+		voucher.ScannedBy = &staffID
+		return tx.Model(&voucher).Updates(map[string]interface{}{
+			"is_used":    true,
+			"used_at":    now,
+			"scanned_by": staffID,
+		}).Error
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to validate voucher")
+		return
+	}
+	if denial != "" {
+		helpers.RespondWithError(c, http.StatusForbidden, denial)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher validated successfully",
+		"voucher": voucherDetails(&voucher),
+	})
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	codeAdmissionRequired = "admission_required"
+	codeInvalidAdmission  = "invalid_admission"
+)
+
+type WaitingRoomRequest struct {
+	IsActive             bool `json:"is_active"`
+	BatchSize            int  `json:"batch_size" binding:"required,min=1"`
+	BatchIntervalSeconds int  `json:"batch_interval_seconds" binding:"required,min=10"`
+	AdmissionMinutes     int  `json:"admission_minutes" binding:"required,min=1"`
+}
+
+func SaveWaitingRoom(c *gin.Context) {
+	var req WaitingRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var room models.WaitingRoom
+	if err := gormDB.Where(models.WaitingRoom{EventID: event.ID}).FirstOrInit(&room).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving waiting room.")
+		return
+	}
+
+	room.IsActive = req.IsActive
+	room.BatchSize = req.BatchSize
+	room.BatchIntervalSeconds = req.BatchIntervalSeconds
+	room.AdmissionMinutes = req.AdmissionMinutes
+
+	if err := gormDB.Save(&room).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to save waiting room.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Waiting room saved successfully.",
+		"waiting_room": room,
+	})
+}
+
+func GetWaitingRoom(c *gin.Context) {
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	event, ok := findOrganizerEvent(c, gormDB)
+	if !ok {
+		return
+	}
+
+	var room models.WaitingRoom
+	if err := gormDB.Where("event_id = ?", event.ID).First(&room).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "This event has no waiting room.")
+		return
+	}
+
+	var counts []struct {
+		Status string
+		Total  int64
+	}
+	err := gormDB.Model(&models.QueueEntry{}).Select("status, COUNT(*) AS total").
+		Where("waiting_room_id = ?", room.ID).Group("status").Scan(&counts).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error counting queue entries.")
+		return
+	}
+
+	totals := gin.H{
+		models.QueueStatusWaiting:  int64(0),
+		models.QueueStatusAdmitted: int64(0),
+		models.QueueStatusExpired:  int64(0),
+		models.QueueStatusUsed:     int64(0),
+	}
+	for _, count := range counts {
+		totals[count.Status] = count.Total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"waiting_room": room,
+		"entries":      totals,
+	})
+}
+
+func JoinQueue(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+	userUUID := userID.(uuid.UUID)
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var room models.WaitingRoom
+	err := gormDB.Joins("Event").Where("waiting_rooms.event_id = ? AND waiting_rooms.is_active = ?", c.Param("id"), true).First(&room).Error
+	if err != nil || room.Event == nil || room.Event.Status != models.EventStatusPublished {
+		helpers.RespondWithError(c, http.StatusNotFound, "No active waiting room for this event.")
+		return
+	}
+
+	var entry models.QueueEntry
+	err = gormDB.Transaction(func(tx *gorm.DB) error {
+		// Locking the room serialises joins so positions stay unique.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&room, "id = ?", room.ID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("waiting_room_id = ? AND user_id = ?", room.ID, userUUID).First(&entry).Error
+		if err == nil && entry.Status != models.QueueStatusExpired && entry.Status != models.QueueStatusUsed {
+			return nil
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		var last int64
+		if err := tx.Model(&models.QueueEntry{}).Select("COALESCE(MAX(position), 0)").Where("waiting_room_id = ?", room.ID).Scan(&last).Error; err != nil {
+			return err
+		}
+
+		// Buyers whose admission lapsed or was used by a checkout rejoin at
+		// the back of the queue.
+		entry.WaitingRoomID = room.ID
+		entry.UserID = userUUID
+		entry.Position = last + 1
+		entry.Status = models.QueueStatusWaiting
+		entry.AdmittedAt = nil
+		entry.ExpiresAt = nil
+		return tx.Save(&entry).Error
+	})
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to join the queue.")
+		return
+	}
+
+	respondWithQueueStatus(c, gormDB, &room, &entry)
+}
+
+func GetQueueStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var room models.WaitingRoom
+	if err := gormDB.Where("event_id = ?", c.Param("id")).First(&room).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "This event has no waiting room.")
+		return
+	}
+
+	var entry models.QueueEntry
+	if err := gormDB.Where("waiting_room_id = ? AND user_id = ?", room.ID, userID).First(&entry).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "You are not in the queue for this event.")
+		return
+	}
+
+	respondWithQueueStatus(c, gormDB, &room, &entry)
+}
+
+// respondWithQueueStatus reports a buyer's place in the queue. Admitted buyers
+// get their admission token, which is reissued on every poll until it expires.
+func respondWithQueueStatus(c *gin.Context, gormDB *gorm.DB, room *models.WaitingRoom, entry *models.QueueEntry) {
+	response := gin.H{
+		"status":   entry.Status,
+		"position": entry.Position,
+	}
+
+	switch entry.Status {
+	case models.QueueStatusWaiting:
+		var ahead int64
+		err := gormDB.Model(&models.QueueEntry{}).
+			Where("waiting_room_id = ? AND status = ? AND position < ?", room.ID, models.QueueStatusWaiting, entry.Position).
+			Count(&ahead).Error
+		if err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving queue position.")
+			return
+		}
+
+		next := room.BatchIntervalSeconds
+		if room.LastAdmittedAt != nil {
+			elapsed := int(time.Since(*room.LastAdmittedAt).Seconds())
+			next = max(room.BatchIntervalSeconds-elapsed, 0)
+		}
+		response["ahead"] = ahead
+		response["estimated_wait_seconds"] = next + int(ahead)/room.BatchSize*room.BatchIntervalSeconds
+
+	case models.QueueStatusAdmitted:
+		if entry.ExpiresAt == nil || !entry.ExpiresAt.After(time.Now()) {
+			response["status"] = models.QueueStatusExpired
+			break
+		}
+		token, err := helpers.SignAdmissionToken(entry.UserID, room.EventID, *entry.ExpiresAt)
+		if err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to generate admission token.")
+			return
+		}
+		response["admission_token"] = token
+		response["expires_at"] = entry.ExpiresAt
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// checkAdmission requires a valid admission token for checkouts of events with
+// an active waiting room.
+func checkAdmission(gormDB *gorm.DB, eventID, userID uuid.UUID, token string) *checkoutError {
+	var room models.WaitingRoom
+	err := gormDB.Where("event_id = ? AND is_active = ?", eventID, true).First(&room).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return &checkoutError{status: http.StatusInternalServerError, message: "Error checking waiting room."}
+	}
+
+	if token == "" {
+		return &checkoutError{http.StatusForbidden, codeAdmissionRequired, "Join the waiting room for this event before checking out."}
+	}
+
+	tokenUserID, tokenEventID, err := helpers.ParseAdmissionToken(token)
+	if err != nil || tokenUserID != userID || tokenEventID != eventID {
+		return &checkoutError{http.StatusForbidden, codeInvalidAdmission, "Admission token is invalid or has expired."}
+	}
+
+	var admitted int64
+	err = gormDB.Model(&models.QueueEntry{}).
+		Where("waiting_room_id = ? AND user_id = ? AND status = ? AND expires_at > ?", room.ID, userID, models.QueueStatusAdmitted, time.Now()).
+		Count(&admitted).Error
+	if err != nil {
+		return &checkoutError{status: http.StatusInternalServerError, message: "Error checking waiting room."}
+	}
+	if admitted == 0 {
+		return &checkoutError{http.StatusForbidden, codeInvalidAdmission, "Admission token is invalid or has expired."}
+	}
+
+	return nil
+}
+
+// consumeAdmission uses up the buyer's admission when their checkout is
+// recorded, so one admission can't be spent on several checkouts. It returns
+// the used entry, or nil when the event has no active waiting room.
+func consumeAdmission(tx *gorm.DB, eventID, userID uuid.UUID) (*models.QueueEntry, *checkoutError) {
+	var room models.WaitingRoom
+	err := tx.Where("event_id = ? AND is_active = ?", eventID, true).First(&room).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &checkoutError{status: http.StatusInternalServerError, message: "Error checking waiting room."}
+	}
+
+	result := tx.Model(&models.QueueEntry{}).
+		Where("waiting_room_id = ? AND user_id = ? AND status = ? AND expires_at > ?", room.ID, userID, models.QueueStatusAdmitted, time.Now()).
+		Update("status", models.QueueStatusUsed)
+	if result.Error != nil {
+		return nil, &checkoutError{status: http.StatusInternalServerError, message: "Error checking waiting room."}
+	}
+	if result.RowsAffected == 0 {
+		return nil, &checkoutError{http.StatusForbidden, codeInvalidAdmission, "Admission token is invalid or has expired."}
+	}
+
+	var entry models.QueueEntry
+	if err := tx.Where("waiting_room_id = ? AND user_id = ?", room.ID, userID).First(&entry).Error; err != nil {
+		return nil, &checkoutError{status: http.StatusInternalServerError, message: "Error checking waiting room."}
+	}
+	return &entry, nil
+}
+
+// restoreAdmission gives an admission back to a buyer whose checkout failed
+// after consuming it. It only applies while the admission is still valid.
+func restoreAdmission(gormDB *gorm.DB, entry *models.QueueEntry) error {
+	return gormDB.Model(&models.QueueEntry{}).
+		Where("id = ? AND status = ? AND expires_at > ?", entry.ID, models.QueueStatusUsed, time.Now()).
+		Update("status", models.QueueStatusAdmitted).Error
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/farellandr/spoticket/internal/waitlist"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const codeSoldOut = "sold_out"
+
+var activeWaitlistStatuses = []string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}
+
+type WaitlistRequest struct {
+	Quantity   int    `json:"quantity" binding:"required,min=1"`
+	AccessCode string `json:"access_code"`
+}
+
+func JoinWaitlist(c *gin.Context) {
+	var req WaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Invalid input. Please check your fields.")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+	userUUID := userID.(uuid.UUID)
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var ticket models.Ticket
+	if err := gormDB.Preload("Event").Where("id = ?", c.Param("id")).First(&ticket).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "Ticket not found.")
+		return
+	}
+
+	if ticket.Event.Status != models.EventStatusPublished || !time.Now().Before(ticketSalesEnd(&ticket, ticket.Event)) {
+		helpers.RespondWithError(c, http.StatusForbidden, "Ticket is not on sale.")
+		return
+	}
+	if _, checkoutErr := checkTicketAccess(gormDB, &ticket, userUUID, req.AccessCode); checkoutErr != nil {
+		helpers.RespondWithErrorCode(c, checkoutErr.status, checkoutErr.code, checkoutErr.message)
+		return
+	}
+
+	// Offers the holder could never check out would block the queue until
+	// they expire, so joining follows the same rules as checkout.
+	var user models.User
+	if err := gormDB.First(&user, userUUID).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "User not found.")
+		return
+	}
+	if checkoutErr := checkPurchaseRules(gormDB, &ticket, &user, req.Quantity); checkoutErr != nil {
+		helpers.RespondWithErrorCode(c, checkoutErr.status, checkoutErr.code, checkoutErr.message)
+		return
+	}
+	if req.Quantity > ticket.Limit {
+		helpers.RespondWithError(c, http.StatusBadRequest, "Quantity exceeds the ticket limit.")
+		return
+	}
+
+	var active int64
+	gormDB.Model(&models.WaitlistEntry{}).Where("ticket_id = ? AND user_id = ? AND status IN ?", ticket.ID, userUUID, activeWaitlistStatuses).Count(&active)
+	if active > 0 {
+		helpers.RespondWithError(c, http.StatusConflict, "You are already on the waitlist for this ticket.")
+		return
+	}
+
+	available, _, err := waitlist.Available(gormDB, &ticket, userUUID)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error checking availability.")
+		return
+	}
+	if available >= req.Quantity {
+		helpers.RespondWithError(c, http.StatusConflict, "Tickets are still available. Buy them directly instead.")
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		TicketID: ticket.ID,
+		UserID:   userUUID,
+		Quantity: req.Quantity,
+		Status:   models.WaitlistStatusWaiting,
+	}
+	if err := gormDB.Create(&entry).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to join the waitlist.")
+		return
+	}
+
+	respondWithWaitlistEntry(c, gormDB, http.StatusCreated, &entry)
+}
+
+func GetWaitlistStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var entry models.WaitlistEntry
+	if err := gormDB.Where("ticket_id = ? AND user_id = ?", c.Param("id"), userID).Order("created_at DESC").First(&entry).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "You are not on the waitlist for this ticket.")
+		return
+	}
+
+	respondWithWaitlistEntry(c, gormDB, http.StatusOK, &entry)
+}
+
+func LeaveWaitlist(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	var entry models.WaitlistEntry
+	if err := gormDB.Where("ticket_id = ? AND user_id = ? AND status IN ?", c.Param("id"), userID, activeWaitlistStatuses).First(&entry).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusNotFound, "You are not on the waitlist for this ticket.")
+		return
+	}
+
+	if err := gormDB.Model(&entry).Update("status", models.WaitlistStatusCancelled).Error; err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Failed to leave the waitlist.")
+		return
+	}
+
+	if err := waitlist.OfferFreedTickets(gormDB, entry.TicketID); err != nil {
+		fmt.Printf("Error offering waitlisted tickets: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "You have left the waitlist.",
+	})
+}
+
+func respondWithWaitlistEntry(c *gin.Context, gormDB *gorm.DB, status int, entry *models.WaitlistEntry) {
+	response := gin.H{
+		"waitlist_id": entry.ID,
+		"status":      entry.Status,
+		"quantity":    entry.Quantity,
+	}
+
+	switch entry.Status {
+	case models.WaitlistStatusWaiting:
+		var ahead int64
+		err := gormDB.Model(&models.WaitlistEntry{}).
+			Where("ticket_id = ? AND status = ? AND created_at < ?", entry.TicketID, models.WaitlistStatusWaiting, entry.CreatedAt).
+			Count(&ahead).Error
+		if err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving waitlist position.")
+			return
+		}
+		response["position"] = ahead + 1
+
+	case models.WaitlistStatusOffered:
+		if entry.OfferExpiresAt != nil && !entry.OfferExpiresAt.After(time.Now()) {
+			response["status"] = models.WaitlistStatusExpired
+			break
+		}
+		response["offer_expires_at"] = entry.OfferExpiresAt
+	}
+
+	c.JSON(status, response)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type walletTicket struct {
+	PurchaseID       uuid.UUID        `json:"purchase_id"`
+	TicketID         uuid.UUID        `json:"ticket_id"`
+	TicketType       string           `json:"ticket_type"`
+	Status           string           `json:"status"`
+	PaymentReference string           `json:"payment_reference"`
+	CheckedInAt      *time.Time       `json:"checked_in_at"`
+	Attendee         *models.Attendee `json:"attendee"`
+}
+
+type walletEvent struct {
+	EventID   uuid.UUID      `json:"event_id"`
+	Title     string         `json:"title"`
+	StartTime time.Time      `json:"start_time"`
+	EndTime   time.Time      `json:"end_time"`
+	Location  string         `json:"location"`
+	City      string         `json:"city"`
+	Tickets   []walletTicket `json:"tickets"`
+}
+
+// purchaseStatus reports a purchase as refunded once the payment behind it is
+// refunded, and as used once it has no entries left. Unlimited tickets never
+// run out, so like any ticket entered at least once they count as used after
+// the event ends, and as expired if never entered.
+func purchaseStatus(purchase *models.Purchase, now time.Time) string {
+	ticket := purchase.Ticket
+	exhausted := purchase.IsUsed
+	if ticket.EntryPolicy == models.EntryPolicyMulti {
+		exhausted = purchase.EntryCount >= ticket.MaxEntries
+	}
+
+	switch {
+	case purchase.Payment != nil && purchase.Payment.Status == "REFUNDED":
+		return "refunded"
+	case exhausted:
+		return "used"
+	case now.After(ticket.Event.EndTime) && purchase.EntryCount > 0:
+		return "used"
+	case now.After(ticket.Event.EndTime):
+		return "expired"
+	default:
+		return "upcoming"
+	}
+}
+
+func ListMyPurchases(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusUnauthorized, "User ID not found in token.")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Database connection not found.")
+		return
+	}
+	gormDB := db.(*gorm.DB)
+
+	pagination, err := helpers.ParsePagination(c)
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	when := c.Query("when")
+
+	now := time.Now()
+	query := gormDB.Model(&models.Purchase{}).
+		Joins("JOIN tickets ON tickets.id = purchases.ticket_id").
+		Joins("JOIN events ON events.id = tickets.event_id").
+		Where("purchases.user_id = ?", userID)
+
+	sortKeys := []helpers.SortKey{{Column: "events.start_time"}, {Column: "purchases.created_at"}}
+	switch when {
+	case "":
+	case "upcoming":
+		query = query.Where("events.end_time >= ?", now)
+	case "past":
+		query = query.Where("events.end_time < ?", now)
+		sortKeys[0].Desc = true
+	default:
+		helpers.RespondWithError(c, http.StatusBadRequest, "Filter must be either upcoming or past.")
+		return
+	}
+
+	result, err := pagination.Fetch(query, "purchases.id", sortKeys, nil)
+	if err != nil {
+		helpers.RespondWithPageError(c, err, "Error retrieving purchases.")
+		return
+	}
+	purchaseIDs := result.IDs
+
+	var purchases []models.Purchase
+	err = gormDB.Preload("Ticket.Event").Preload("Payment").Preload("Attendee").
+		Where("id IN ?", purchaseIDs).Find(&purchases).Error
+	if err != nil {
+		helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving purchases.")
+		return
+	}
+	purchases = helpers.SortByIDs(purchases, purchaseIDs, func(purchase *models.Purchase) uuid.UUID { return purchase.ID })
+
+	var firstCheckIns []struct {
+		PurchaseID  uuid.UUID
+		CheckedInAt time.Time
+	}
+	if len(purchaseIDs) > 0 {
+		err = gormDB.Model(&models.CheckIn{}).
+			Select("purchase_id, MIN(created_at) AS checked_in_at").
+			Where("purchase_id IN ? AND direction = ?", purchaseIDs, models.CheckInDirectionIn).
+			Group("purchase_id").
+			Scan(&firstCheckIns).Error
+		if err != nil {
+			helpers.RespondWithError(c, http.StatusInternalServerError, "Error retrieving check-ins.")
+			return
+		}
+	}
+
+	checkedInAt := make(map[uuid.UUID]time.Time, len(firstCheckIns))
+	for _, checkIn := range firstCheckIns {
+		checkedInAt[checkIn.PurchaseID] = checkIn.CheckedInAt
+	}
+
+	events := []*walletEvent{}
+	byEvent := make(map[uuid.UUID]*walletEvent)
+	for i := range purchases {
+		purchase := &purchases[i]
+		event := purchase.Ticket.Event
+
+		group, ok := byEvent[event.ID]
+		if !ok {
+			group = &walletEvent{
+				EventID:   event.ID,
+				Title:     event.Title,
+				StartTime: event.StartTime,
+				EndTime:   event.EndTime,
+				Location:  event.Location,
+				City:      event.City,
+			}
+			byEvent[event.ID] = group
+			events = append(events, group)
+		}
+
+		entry := walletTicket{
+			PurchaseID: purchase.ID,
+			TicketID:   purchase.TicketID,
+			TicketType: purchase.Ticket.Type,
+			Status:     purchaseStatus(purchase, now),
+			Attendee:   purchase.Attendee,
+		}
+		if purchase.Payment != nil {
+			entry.PaymentReference = purchase.Payment.TransactionID
+		}
+		if t, ok := checkedInAt[purchase.ID]; ok {
+			entry.CheckedInAt = &t
+		}
+
+		group.Tickets = append(group.Tickets, entry)
+	}
+
+	response := pagination.Response(result)
+	response["events"] = events
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const admissionTokenType = "admission"
+
+var ErrInvalidAdmissionToken = errors.New("invalid admission token")
+
+// SignAdmissionToken issues the token a queued buyer presents at checkout. It
+// deliberately has no user_id claim so it can't be used as a login token.
+func SignAdmissionToken(userID, eventID uuid.UUID, expiresAt time.Time) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET not configured")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":   admissionTokenType,
+		"sub":   userID.String(),
+		"event": eventID.String(),
+		"exp":   expiresAt.Unix(),
+	})
+	return token.SignedString([]byte(secret))
+}
+
+// ParseAdmissionToken verifies an admission token and returns the user and
+// event it was issued for.
+func ParseAdmissionToken(tokenString string) (uuid.UUID, uuid.UUID, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	}, jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return uuid.Nil, uuid.Nil, ErrInvalidAdmissionToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != admissionTokenType {
+		return uuid.Nil, uuid.Nil, ErrInvalidAdmissionToken
+	}
+
+	subject, _ := claims["sub"].(string)
+	event, _ := claims["event"].(string)
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidAdmissionToken
+	}
+	eventID, err := uuid.Parse(event)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidAdmissionToken
+	}
+	return userID, eventID, nil
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CheckInEvent struct {
+	EventID    uuid.UUID `json:"event_id"`
+	PurchaseID uuid.UUID `json:"purchase_id"`
+	TicketID   uuid.UUID `json:"ticket_id"`
+	TicketType string    `json:"ticket_type"`
+	Direction  string    `json:"direction"`
+	Gate       string    `json:"gate"`
+	ScannedAt  time.Time `json:"scanned_at"`
+}
+
+// CheckInHub fans out check-in scans to the dashboards watching an event.
+type CheckInHub struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan CheckInEvent]struct{}
+}
+
+func NewCheckInHub() *CheckInHub {
+	return &CheckInHub{
+		subscribers: make(map[uuid.UUID]map[chan CheckInEvent]struct{}),
+	}
+}
+
+func (h *CheckInHub) Subscribe(eventID uuid.UUID) chan CheckInEvent {
+	ch := make(chan CheckInEvent, 32)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[eventID] == nil {
+		h.subscribers[eventID] = make(map[chan CheckInEvent]struct{})
+	}
+	h.subscribers[eventID][ch] = struct{}{}
+
+	return ch
+}
+
+func (h *CheckInHub) Unsubscribe(eventID uuid.UUID, ch chan CheckInEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers[eventID], ch)
+	if len(h.subscribers[eventID]) == 0 {
+		delete(h.subscribers, eventID)
+	}
+	close(ch)
+}
+
+// Publish never blocks the scanner; slow subscribers simply miss events.
+func (h *CheckInHub) Publish(event CheckInEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[event.EventID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
 }
 
 func HTTPStatusText(code int) string {
@@ -21,3 +22,13 @@ func RespondWithError(c *gin.Context, statusCode int, customMessage string) {
 		Message: customMessage,
 	})
 }
+
+// RespondWithErrorCode adds a machine readable code for errors clients are
+// expected to handle, such as checkout rule violations.
+func RespondWithErrorCode(c *gin.Context, statusCode int, code string, customMessage string) {
+	c.JSON(statusCode, ErrorResponse{
+		Error:   HTTPStatusText(statusCode),
+		Message: customMessage,
+		Code:    code,
+	})
+}
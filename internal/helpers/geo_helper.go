@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+)
+
+const earthRadiusKm = 6371.0
+
+type BoundingBox struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// NewBoundingBox returns a box that contains every point within radiusKm of
+// the centre. It is used to narrow rows before the exact haversine check.
+func NewBoundingBox(lat, lng, radiusKm float64) BoundingBox {
+	latDelta := radiusKm / earthRadiusKm * 180 / math.Pi
+
+	lngDelta := 180.0
+	if cos := math.Cos(lat * math.Pi / 180); cos > 1e-6 {
+		lngDelta = math.Min(latDelta/cos, 180)
+	}
+
+	return BoundingBox{
+		MinLat: math.Max(lat-latDelta, -90),
+		MaxLat: math.Min(lat+latDelta, 90),
+		MinLng: lng - lngDelta,
+		MaxLng: lng + lngDelta,
+	}
+}
+
+// WrapsLongitude reports whether the box crosses the antimeridian, in which
+// case the longitude bounds cannot be used as a simple range.
+func (b BoundingBox) WrapsLongitude() bool {
+	return b.MinLng < -180 || b.MaxLng > 180
+}
+
+// HaversineSQL returns a SQL expression for the distance in kilometres between
+// the given columns and the point bound to the named parameters @lat and @lng.
+func HaversineSQL(latColumn, lngColumn string) string {
+	return fmt.Sprintf(
+		"(%[3]f * 2 * ASIN(LEAST(1, SQRT(POWER(SIN(RADIANS(%[1]s - @lat) / 2), 2) + COS(RADIANS(@lat)) * COS(RADIANS(%[1]s)) * POWER(SIN(RADIANS(%[2]s - @lng) / 2), 2)))))",
+		latColumn, lngColumn, earthRadiusKm,
+	)
+}
@@ -0,0 +1,336 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortKey is one ORDER BY term of a keyset. Column may be any SQL expression
+// that never evaluates to NULL.
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+type cursorState struct {
+	Signature string        `json:"s"`
+	Values    []cursorValue `json:"v"`
+}
+
+// Pagination holds the paging parameters of a list request. Requests with a
+// cursor use keyset pagination; all others fall back to page/limit offsets so
+// existing clients keep working.
+type Pagination struct {
+	Limit        int
+	Page         int
+	IncludeTotal bool
+	cursor       *cursorState
+}
+
+type PageResult struct {
+	IDs        []uuid.UUID
+	Total      *int64
+	NextCursor *string
+}
+
+func ParsePagination(c *gin.Context) (*Pagination, error) {
+	p := &Pagination{Limit: DefaultPageSize, Page: 1}
+
+	if limit := c.Query("limit"); limit != "" {
+		limitNum, err := strconv.Atoi(limit)
+		if err != nil || limitNum < 1 {
+			return nil, errors.New("Invalid limit.")
+		}
+		p.Limit = min(limitNum, MaxPageSize)
+	}
+
+	cursor := c.Query("cursor")
+	page := c.Query("page")
+	if cursor != "" && page != "" {
+		return nil, errors.New("Use either cursor or page, not both.")
+	}
+
+	if cursor != "" {
+		state, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, errors.New("Invalid cursor.")
+		}
+		p.cursor = state
+		p.Page = 0
+	} else if page != "" {
+		pageNum, err := strconv.Atoi(page)
+		if err != nil || pageNum < 1 {
+			return nil, errors.New("Invalid page number.")
+		}
+		p.Page = pageNum
+	}
+
+	p.IncludeTotal = p.cursor == nil
+	if includeTotal := c.Query("include_total"); includeTotal != "" {
+		flag, err := strconv.ParseBool(includeTotal)
+		if err != nil {
+			return nil, errors.New("Invalid include_total.")
+		}
+		p.IncludeTotal = flag
+	}
+
+	return p, nil
+}
+
+// Fetch returns the IDs of one page of query, ordered by keys and then by
+// idColumn. Named parameters used by the key expressions go in params.
+func (p *Pagination) Fetch(query *gorm.DB, idColumn string, keys []SortKey, params map[string]interface{}) (*PageResult, error) {
+	keys = append(append([]SortKey{}, keys...), SortKey{Column: idColumn})
+	signature := keysetSignature(keys)
+
+	result := &PageResult{}
+	if p.IncludeTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
+
+	args := make(map[string]interface{}, len(params)+len(keys))
+	for name, value := range params {
+		args[name] = value
+	}
+
+	selects := make([]string, len(keys))
+	orders := make([]string, len(keys))
+	for i, key := range keys {
+		selects[i] = fmt.Sprintf("%s AS cursor_%d", key.Column, i)
+		orders[i] = fmt.Sprintf("cursor_%d", i)
+		if key.Desc {
+			orders[i] += " DESC"
+		}
+	}
+
+	rowsQuery := query.Session(&gorm.Session{}).Select(strings.Join(selects, ", "), args).Order(strings.Join(orders, ", "))
+	if p.cursor != nil {
+		condition, err := p.keysetCondition(keys, signature, args)
+		if err != nil {
+			return nil, err
+		}
+		rowsQuery = rowsQuery.Where(condition, args)
+	} else {
+		rowsQuery = rowsQuery.Offset((p.Page - 1) * p.Limit)
+	}
+
+	var rows []map[string]interface{}
+	if err := rowsQuery.Limit(p.Limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := len(rows) > p.Limit
+	if hasMore {
+		rows = rows[:p.Limit]
+	}
+
+	idKey := fmt.Sprintf("cursor_%d", len(keys)-1)
+	for _, row := range rows {
+		id, err := uuidValue(row[idKey])
+		if err != nil {
+			return nil, err
+		}
+		result.IDs = append(result.IDs, id)
+	}
+
+	if hasMore {
+		state := cursorState{Signature: signature}
+		for i := range keys {
+			value, err := encodeCursorValue(rows[len(rows)-1][fmt.Sprintf("cursor_%d", i)])
+			if err != nil {
+				return nil, err
+			}
+			state.Values = append(state.Values, value)
+		}
+		cursor, err := encodeCursor(state)
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = &cursor
+	}
+
+	return result, nil
+}
+
+// Response returns the pagination fields of a list response.
+func (p *Pagination) Response(result *PageResult) gin.H {
+	response := gin.H{
+		"limit":       p.Limit,
+		"next_cursor": result.NextCursor,
+	}
+	if result.Total != nil {
+		response["total"] = *result.Total
+		response["total_pages"] = (*result.Total + int64(p.Limit) - 1) / int64(p.Limit)
+	}
+	if p.cursor == nil {
+		response["page"] = p.Page
+	}
+	return response
+}
+
+// SortByIDs reorders items to match ids, which is the order Fetch returned.
+func SortByIDs[T any](items []T, ids []uuid.UUID, id func(*T) uuid.UUID) []T {
+	byID := make(map[uuid.UUID]*T, len(items))
+	for i := range items {
+		byID[id(&items[i])] = &items[i]
+	}
+
+	sorted := make([]T, 0, len(items))
+	for _, itemID := range ids {
+		if item, ok := byID[itemID]; ok {
+			sorted = append(sorted, *item)
+		}
+	}
+	return sorted
+}
+
+// keysetCondition builds "rows after the cursor" for keys with mixed
+// directions: (k0 > v0) OR (k0 = v0 AND k1 > v1) OR ...
+func (p *Pagination) keysetCondition(keys []SortKey, signature string, args map[string]interface{}) (string, error) {
+	if p.cursor.Signature != signature || len(p.cursor.Values) != len(keys) {
+		return "", ErrInvalidCursor
+	}
+
+	var alternatives []string
+	for i, key := range keys {
+		value, err := decodeCursorValue(p.cursor.Values[i])
+		if err != nil {
+			return "", ErrInvalidCursor
+		}
+		args[fmt.Sprintf("cursor_%d", i)] = value
+
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = @cursor_%d", keys[j].Column, j))
+		}
+		operator := ">"
+		if key.Desc {
+			operator = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s @cursor_%d", key.Column, operator, i))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
+
+func keysetSignature(keys []SortKey) string {
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s:%t;", key.Column, key.Desc)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func encodeCursor(state cursorState) (string, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string) (*cursorState, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var state cursorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func encodeCursorValue(value interface{}) (cursorValue, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return cursorValue{Type: "time", Value: v.Format(time.RFC3339Nano)}, nil
+	case float64:
+		return cursorValue{Type: "float", Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case float32:
+		return cursorValue{Type: "float", Value: strconv.FormatFloat(float64(v), 'g', -1, 32)}, nil
+	case int64:
+		return cursorValue{Type: "int", Value: strconv.FormatInt(v, 10)}, nil
+	case int32:
+		return cursorValue{Type: "int", Value: strconv.FormatInt(int64(v), 10)}, nil
+	case int:
+		return cursorValue{Type: "int", Value: strconv.Itoa(v)}, nil
+	case string:
+		return cursorValue{Type: "string", Value: v}, nil
+	case []byte:
+		return cursorValue{Type: "string", Value: string(v)}, nil
+	case [16]byte:
+		return cursorValue{Type: "string", Value: uuid.UUID(v).String()}, nil
+	case uuid.UUID:
+		return cursorValue{Type: "string", Value: v.String()}, nil
+	default:
+		return cursorValue{}, fmt.Errorf("unsupported cursor value %T", value)
+	}
+}
+
+func decodeCursorValue(value cursorValue) (interface{}, error) {
+	switch value.Type {
+	case "time":
+		return time.Parse(time.RFC3339Nano, value.Value)
+	case "float":
+		return strconv.ParseFloat(value.Value, 64)
+	case "int":
+		return strconv.ParseInt(value.Value, 10, 64)
+	case "string":
+		return value.Value, nil
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
+
+func uuidValue(value interface{}) (uuid.UUID, error) {
+	switch v := value.(type) {
+	case string:
+		return uuid.Parse(v)
+	case []byte:
+		return uuid.ParseBytes(v)
+	case [16]byte:
+		return uuid.UUID(v), nil
+	default:
+		return uuid.Nil, fmt.Errorf("unsupported id value %T", value)
+	}
+}
+
+// RespondWithPageError reports a Fetch failure, separating stale or tampered
+// cursors from database errors.
+func RespondWithPageError(c *gin.Context, err error, message string) {
+	if errors.Is(err, ErrInvalidCursor) {
+		RespondWithError(c, http.StatusBadRequest, "Invalid cursor.")
+		return
+	}
+	RespondWithError(c, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,200 @@
+package helpers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestCursorValueRoundTrip(t *testing.T) {
+	id := uuid.MustParse("4f1c2d3e-5a6b-4c7d-8e9f-0a1b2c3d4e5f")
+	startTime := time.Date(2026, 3, 14, 19, 30, 0, 123456789, time.FixedZone("WIB", 7*60*60))
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "time", value: startTime, want: startTime},
+		{name: "float rank", value: 0.0607927101854027, want: 0.0607927101854027},
+		{name: "float32 rank", value: float32(0.25), want: 0.25},
+		{name: "negative float", value: -1.5e-7, want: -1.5e-7},
+		{name: "int", value: int64(42), want: int64(42)},
+		{name: "int32", value: int32(-7), want: int64(-7)},
+		{name: "string", value: "Jakarta", want: "Jakarta"},
+		{name: "uuid string", value: id.String(), want: id.String()},
+		{name: "uuid bytes", value: [16]byte(id), want: id.String()},
+		{name: "uuid", value: id, want: id.String()},
+		{name: "text bytes", value: []byte("Bandung"), want: "Bandung"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeCursorValue(tt.value)
+			if err != nil {
+				t.Fatalf("encodeCursorValue: %v", err)
+			}
+
+			// Values travel inside the cursor, so round-trip the whole state.
+			cursor, err := encodeCursor(cursorState{Signature: "sig", Values: []cursorValue{encoded}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, err := decodeCursor(cursor)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+
+			got, err := decodeCursorValue(state.Values[0])
+			if err != nil {
+				t.Fatalf("decodeCursorValue: %v", err)
+			}
+			if want, ok := tt.want.(time.Time); ok {
+				if !got.(time.Time).Equal(want) {
+					t.Errorf("got %v, want %v", got, want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("got %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorValueRejects(t *testing.T) {
+	if _, err := encodeCursorValue(true); err == nil {
+		t.Error("encoded an unsupported value")
+	}
+	for _, value := range []cursorValue{
+		{Type: "time", Value: "yesterday"},
+		{Type: "float", Value: "high"},
+		{Type: "int", Value: "1.5"},
+		{Type: "bool", Value: "true"},
+	} {
+		if _, err := decodeCursorValue(value); err == nil {
+			t.Errorf("decodeCursorValue(%+v) succeeded, want error", value)
+		}
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	startTime := time.Date(2026, 3, 14, 19, 30, 0, 0, time.UTC)
+	id := uuid.MustParse("4f1c2d3e-5a6b-4c7d-8e9f-0a1b2c3d4e5f")
+	keys := []SortKey{
+		{Column: "events.start_time", Desc: true},
+		{Column: "rank"},
+		{Column: "events.id"},
+	}
+
+	var values []cursorValue
+	for _, value := range []interface{}{startTime, 0.5, id} {
+		encoded, err := encodeCursorValue(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, encoded)
+	}
+	p := &Pagination{cursor: &cursorState{Signature: keysetSignature(keys), Values: values}}
+
+	args := map[string]interface{}{"search": "jazz"}
+	condition, err := p.keysetCondition(keys, keysetSignature(keys), args)
+	if err != nil {
+		t.Fatalf("keysetCondition: %v", err)
+	}
+
+	want := "((events.start_time < @cursor_0)" +
+		" OR (events.start_time = @cursor_0 AND rank > @cursor_1)" +
+		" OR (events.start_time = @cursor_0 AND rank = @cursor_1 AND events.id > @cursor_2))"
+	if condition != want {
+		t.Errorf("condition =\n%s\nwant\n%s", condition, want)
+	}
+
+	if got, ok := args["cursor_0"].(time.Time); !ok || !got.Equal(startTime) {
+		t.Errorf("cursor_0 = %v, want %v", args["cursor_0"], startTime)
+	}
+	if args["cursor_1"] != 0.5 {
+		t.Errorf("cursor_1 = %v, want 0.5", args["cursor_1"])
+	}
+	if args["cursor_2"] != id.String() {
+		t.Errorf("cursor_2 = %v, want %s", args["cursor_2"], id)
+	}
+	if args["search"] != "jazz" {
+		t.Error("existing params were overwritten")
+	}
+}
+
+func TestKeysetConditionRejectsStaleCursor(t *testing.T) {
+	keys := []SortKey{{Column: "events.start_time"}, {Column: "events.id"}}
+	signature := keysetSignature(keys)
+	values := []cursorValue{
+		{Type: "time", Value: "2026-03-14T19:30:00Z"},
+		{Type: "string", Value: "4f1c2d3e-5a6b-4c7d-8e9f-0a1b2c3d4e5f"},
+	}
+
+	// The same columns sorted the other way must not reuse the cursor.
+	descending := []SortKey{{Column: "events.start_time", Desc: true}, {Column: "events.id"}}
+	if keysetSignature(descending) == signature {
+		t.Fatal("signature ignores sort direction")
+	}
+
+	tests := []struct {
+		name  string
+		state cursorState
+	}{
+		{name: "other sort", state: cursorState{Signature: keysetSignature(descending), Values: values}},
+		{name: "tampered signature", state: cursorState{Signature: "0000000000000000", Values: values}},
+		{name: "missing value", state: cursorState{Signature: signature, Values: values[:1]}},
+		{name: "bad value", state: cursorState{Signature: signature, Values: []cursorValue{values[0], {Type: "uuid", Value: values[1].Value}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pagination{cursor: &tt.state}
+			_, err := p.keysetCondition(keys, signature, map[string]interface{}{})
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(query string) (*Pagination, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+		return ParsePagination(c)
+	}
+
+	cursor, err := encodeCursor(cursorState{Signature: "sig", Values: []cursorValue{{Type: "int", Value: "1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := parse("limit=500&cursor=" + cursor)
+	if err != nil {
+		t.Fatalf("ParsePagination: %v", err)
+	}
+	if p.Limit != MaxPageSize || p.Page != 0 || p.IncludeTotal || p.cursor == nil {
+		t.Errorf("cursor pagination = %+v", p)
+	}
+
+	p, err = parse("page=3")
+	if err != nil {
+		t.Fatalf("ParsePagination: %v", err)
+	}
+	if p.Limit != DefaultPageSize || p.Page != 3 || !p.IncludeTotal || p.cursor != nil {
+		t.Errorf("page pagination = %+v", p)
+	}
+
+	for _, query := range []string{"cursor=not-a-cursor!", "cursor=" + cursor + "&page=2", "page=0", "limit=0", "include_total=maybe"} {
+		if _, err := parse(query); err == nil {
+			t.Errorf("ParsePagination(%q) succeeded, want error", query)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const dateLayout = "2006-01-02"
+
+// QueryFilters parses optional list filters from the query string. Each
+// getter returns nil when the parameter is absent; the first invalid value is
+// kept and reported by Err.
+type QueryFilters struct {
+	c   *gin.Context
+	err error
+}
+
+func NewQueryFilters(c *gin.Context) *QueryFilters {
+	return &QueryFilters{c: c}
+}
+
+func (f *QueryFilters) Err() error {
+	return f.err
+}
+
+func (f *QueryFilters) fail(format string, args ...interface{}) {
+	if f.err == nil {
+		f.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (f *QueryFilters) String(name string) string {
+	return strings.TrimSpace(f.c.Query(name))
+}
+
+// List splits a comma separated parameter, dropping empty entries.
+func (f *QueryFilters) List(name string) []string {
+	var values []string
+	for _, value := range strings.Split(f.c.Query(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (f *QueryFilters) UUID(name string) *uuid.UUID {
+	value := f.String(name)
+	if value == "" {
+		return nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		f.fail("Invalid %s.", name)
+		return nil
+	}
+	return &id
+}
+
+func (f *QueryFilters) Int(name string) *int {
+	value := f.String(name)
+	if value == "" {
+		return nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		f.fail("Invalid %s.", name)
+		return nil
+	}
+	return &number
+}
+
+func (f *QueryFilters) Bool(name string) *bool {
+	value := f.String(name)
+	if value == "" {
+		return nil
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		f.fail("Invalid %s.", name)
+		return nil
+	}
+	return &flag
+}
+
+// TimeRange reads two RFC3339 or YYYY-MM-DD parameters. A date-only upper
+// bound covers the whole day.
+func (f *QueryFilters) TimeRange(fromName, toName string) (*time.Time, *time.Time) {
+	from := f.time(fromName, false)
+	to := f.time(toName, true)
+	if from != nil && to != nil && to.Before(*from) {
+		f.fail("%s must not be before %s.", toName, fromName)
+	}
+	return from, to
+}
+
+func (f *QueryFilters) time(name string, endOfDay bool) *time.Time {
+	value := f.String(name)
+	if value == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		f.fail("Invalid %s.", name)
+		return nil
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t
+}
+
+// IntRange reads a pair of non-negative integer bounds.
+func (f *QueryFilters) IntRange(minName, maxName string) (*int, *int) {
+	min := f.Int(minName)
+	max := f.Int(maxName)
+	if min != nil && max != nil && *max < *min {
+		f.fail("%s must not be less than %s.", maxName, minName)
+	}
+	return min, max
+}
+
+// Sort maps the sort parameter to its keyset. It returns nil when no sort was
+// requested.
+func (f *QueryFilters) Sort(options map[string][]SortKey) []SortKey {
+	value := f.String("sort")
+	if value == "" {
+		return nil
+	}
+	keys, ok := options[value]
+	if !ok {
+		names := make([]string, 0, len(options))
+		for name := range options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		f.fail("Invalid sort. Use one of: %s.", strings.Join(names, ", "))
+		return nil
+	}
+	return keys
+}
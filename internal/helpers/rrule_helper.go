@@ -0,0 +1,238 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RRule is the subset of RFC 5545 recurrence rules supported for event
+// series: FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, COUNT, UNTIL, BYDAY
+// without ordinals and BYMONTHDAY. A rule must be bounded by COUNT or UNTIL.
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+
+	// untilDate marks a date-only UNTIL, which covers the whole day in the
+	// timezone of the series start.
+	untilDate bool
+}
+
+func ParseRRule(value string) (*RRule, error) {
+	rule := &RRule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+
+	for _, part := range strings.Split(value, ";") {
+		name, arg, found := strings.Cut(part, "=")
+		if !found || arg == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(arg)
+			if rule.Freq != FreqDaily && rule.Freq != FreqWeekly && rule.Freq != FreqMonthly {
+				return nil, fmt.Errorf("unsupported FREQ %s", arg)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(arg)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %s", arg)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(arg)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %s", arg)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, dateOnly, err := parseRRuleTime(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %s", arg)
+			}
+			rule.Until = &until
+			rule.untilDate = dateOnly
+		case "BYDAY":
+			for _, day := range strings.Split(arg, ",") {
+				weekday, ok := rruleWeekdays[strings.ToUpper(day)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY %s", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(arg, ",") {
+				monthDay, err := strconv.Atoi(day)
+				if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %s", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, monthDay)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count == 0 && rule.Until == nil {
+		return nil, fmt.Errorf("COUNT or UNTIL is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+
+	return rule, nil
+}
+
+func parseRRuleTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
+
+// until returns the last instant the rule covers for a series starting at
+// start, or nil when the rule is bounded by COUNT.
+func (r *RRule) until(start time.Time) *time.Time {
+	if r.Until == nil || !r.untilDate {
+		return r.Until
+	}
+	end := time.Date(r.Until.Year(), r.Until.Month(), r.Until.Day(), 23, 59, 59, 0, start.Location())
+	return &end
+}
+
+// Occurrences expands the rule from start, returning at most limit start
+// times. The first occurrence is always start itself.
+func (r *RRule) Occurrences(start time.Time, limit int) []time.Time {
+	occurrences := []time.Time{start}
+	until := r.until(start)
+	emit := func(t time.Time) bool {
+		if !t.After(start) {
+			return true
+		}
+		if until != nil && t.After(*until) {
+			return false
+		}
+		if r.Count > 0 && len(occurrences) >= r.Count {
+			return false
+		}
+		occurrences = append(occurrences, t)
+		return len(occurrences) < limit
+	}
+
+	// Each period is a day, week or month depending on FREQ; the loop stops
+	// once the rule is exhausted or after a bounded number of empty periods.
+	for period := 0; period < 10000 && len(occurrences) < limit; period++ {
+		for _, candidate := range r.candidates(start, period*r.Interval) {
+			if !emit(candidate) {
+				return occurrences
+			}
+		}
+	}
+
+	return occurrences
+}
+
+func (r *RRule) candidates(start time.Time, offset int) []time.Time {
+	switch r.Freq {
+	case FreqDaily:
+		day := start.AddDate(0, 0, offset)
+		if r.matchesDay(day) && r.matchesMonthDay(day) {
+			return []time.Time{day}
+		}
+		return nil
+
+	case FreqWeekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		weekStart := start.AddDate(0, 0, -mondayOffset(start.Weekday())+7*offset)
+
+		var candidates []time.Time
+		for _, weekday := range days {
+			candidates = append(candidates, weekStart.AddDate(0, 0, mondayOffset(weekday)))
+		}
+		sortTimes(candidates)
+		return candidates
+
+	case FreqMonthly:
+		monthStart := time.Date(start.Year(), start.Month()+time.Month(offset), 1, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+		daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+
+		var candidates []time.Time
+		for day := 1; day <= daysInMonth; day++ {
+			candidate := monthStart.AddDate(0, 0, day-1)
+			if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && day != start.Day() {
+				continue
+			}
+			if r.matchesDay(candidate) && r.matchesMonthDay(candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		return candidates
+	}
+	return nil
+}
+
+func (r *RRule) matchesDay(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, weekday := range r.ByDay {
+		if t.Weekday() == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay == t.Day() || (monthDay < 0 && daysInMonth+monthDay+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func mondayOffset(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+}
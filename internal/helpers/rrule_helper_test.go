@@ -0,0 +1,125 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRRuleOccurrences(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*60*60)
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, wib)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		limit int
+		want  []time.Time
+	}{
+		{
+			name:  "daily count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: at(2026, 1, 5, 19),
+			want:  []time.Time{at(2026, 1, 5, 19), at(2026, 1, 6, 19), at(2026, 1, 7, 19)},
+		},
+		{
+			name:  "count of one is the start only",
+			rule:  "FREQ=WEEKLY;COUNT=1",
+			start: at(2026, 1, 5, 19),
+			want:  []time.Time{at(2026, 1, 5, 19)},
+		},
+		{
+			name:  "limit below count",
+			rule:  "FREQ=DAILY;COUNT=10",
+			start: at(2026, 1, 5, 19),
+			limit: 2,
+			want:  []time.Time{at(2026, 1, 5, 19), at(2026, 1, 6, 19)},
+		},
+		{
+			name:  "weekly by day every other week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=5",
+			start: at(2026, 1, 5, 19),
+			want:  []time.Time{at(2026, 1, 5, 19), at(2026, 1, 7, 19), at(2026, 1, 19, 19), at(2026, 1, 21, 19), at(2026, 2, 2, 19)},
+		},
+		{
+			name:  "weekly by day starting mid-week",
+			rule:  "RRULE:FREQ=WEEKLY;BYDAY=TU,TH;COUNT=4",
+			start: at(2026, 1, 7, 19),
+			want:  []time.Time{at(2026, 1, 7, 19), at(2026, 1, 8, 19), at(2026, 1, 13, 19), at(2026, 1, 15, 19)},
+		},
+		{
+			name:  "last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4",
+			start: at(2026, 1, 31, 19),
+			want:  []time.Time{at(2026, 1, 31, 19), at(2026, 2, 28, 19), at(2026, 3, 31, 19), at(2026, 4, 30, 19)},
+		},
+		{
+			name:  "second to last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-2;COUNT=3",
+			start: at(2026, 1, 30, 19),
+			want:  []time.Time{at(2026, 1, 30, 19), at(2026, 2, 27, 19), at(2026, 3, 30, 19)},
+		},
+		{
+			name:  "date-only until covers the local day",
+			rule:  "FREQ=DAILY;UNTIL=20260107",
+			start: at(2026, 1, 5, 5),
+			want:  []time.Time{at(2026, 1, 5, 5), at(2026, 1, 6, 5), at(2026, 1, 7, 5)},
+		},
+		{
+			name:  "until timestamp is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260106T220000Z",
+			start: at(2026, 1, 5, 5),
+			want:  []time.Time{at(2026, 1, 5, 5), at(2026, 1, 6, 5), at(2026, 1, 7, 5)},
+		},
+		{
+			name:  "until before start",
+			rule:  "FREQ=DAILY;UNTIL=20260101",
+			start: at(2026, 1, 5, 19),
+			want:  []time.Time{at(2026, 1, 5, 19)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule: %v", err)
+			}
+			limit := tt.limit
+			if limit == 0 {
+				limit = 100
+			}
+
+			got := rule.Occurrences(tt.start, limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %d %v", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRRuleRejects(t *testing.T) {
+	for _, value := range []string{
+		"COUNT=3",
+		"FREQ=YEARLY;COUNT=3",
+		"FREQ=DAILY",
+		"FREQ=DAILY;COUNT=3;UNTIL=20260107",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;INTERVAL=0;COUNT=3",
+		"FREQ=WEEKLY;BYDAY=1MO;COUNT=3",
+		"FREQ=MONTHLY;BYMONTHDAY=0;COUNT=3",
+		"FREQ=MONTHLY;BYMONTHDAY=-32;COUNT=3",
+		"FREQ=DAILY;UNTIL=2026-01-07",
+	} {
+		if _, err := ParseRRule(value); err == nil {
+			t.Errorf("ParseRRule(%q) succeeded, want error", value)
+		}
+	}
+}
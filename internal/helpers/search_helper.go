@@ -0,0 +1,18 @@
+package helpers
+
+// SearchQuerySQL is the tsquery for the named parameter @q, parsed with both
+// the English and Indonesian configurations so either stem form matches.
+const SearchQuerySQL = "(websearch_to_tsquery('english', @q) || websearch_to_tsquery('indonesian', @q))"
+
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// SearchRankSQL ranks vectorColumn against SearchQuerySQL.
+func SearchRankSQL(vectorColumn string) string {
+	return "ts_rank_cd(" + vectorColumn + ", " + SearchQuerySQL + ")"
+}
+
+// SearchHeadlineSQL returns matched snippets of documentSQL wrapped in <mark>
+// tags.
+func SearchHeadlineSQL(documentSQL string) string {
+	return "ts_headline('english', " + documentSQL + ", " + SearchQuerySQL + ", '" + searchHeadlineOptions + "')"
+}
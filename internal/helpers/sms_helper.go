@@ -0,0 +1,55 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SMSSender delivers text messages through an HTTP gateway that accepts
+// {"to": ..., "message": ...} with a bearer token.
+type SMSSender struct {
+	GatewayURL string
+	Token      string
+	Client     *http.Client
+}
+
+func NewSMSSender(gatewayURL, token string) *SMSSender {
+	return &SMSSender{
+		GatewayURL: gatewayURL,
+		Token:      token,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *SMSSender) Send(to, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"to":      to,
+		"message": message,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.GatewayURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sms gateway responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const GoogleWalletSaveURL = "https://pay.google.com/gp/v/save/"
+
+// WalletIssuer holds the identities used to sign Apple Wallet passes and
+// Google Wallet save links. Either half may be nil when not configured.
+type WalletIssuer struct {
+	PassTypeIdentifier string
+	TeamIdentifier     string
+	OrganizationName   string
+	AppleSigner        *PassSigner
+
+	GoogleIssuerID            string
+	GoogleServiceAccountEmail string
+	GoogleKey                 *rsa.PrivateKey
+}
+
+type PassSigner struct {
+	Certificate  *x509.Certificate
+	PrivateKey   crypto.Signer
+	Intermediate *x509.Certificate
+}
+
+func LoadPassSigner(certPath, keyPath, wwdrPath string) (*PassSigner, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	wwdrPEM, err := os.ReadFile(wwdrPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePassSigner(certPEM, keyPEM, wwdrPEM)
+}
+
+func ParsePassSigner(certPEM, keyPEM, wwdrPEM []byte) (*PassSigner, error) {
+	cert, err := parseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pass certificate: %v", err)
+	}
+	wwdr, err := parseCertificatePEM(wwdrPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WWDR certificate: %v", err)
+	}
+	key, err := ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pass key: %v", err)
+	}
+
+	return &PassSigner{Certificate: cert, PrivateKey: key, Intermediate: wwdr}, nil
+}
+
+func parseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type")
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported private key format")
+}
+
+// BuildPKPass zips the pass files together with a manifest of their SHA-1
+// hashes and a detached PKCS#7 signature of that manifest.
+func BuildPKPass(files map[string][]byte, signer *PassSigner) ([]byte, error) {
+	manifest := make(map[string]string, len(files))
+	for name, content := range files {
+		sum := sha1.Sum(content)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := SignDetachedPKCS7(manifestJSON, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	write := func(name string, content []byte) error {
+		w, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+
+	for _, name := range names {
+		if err := write(name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := write("manifest.json", manifestJSON); err != nil {
+		return nil, err
+	}
+	if err := write("signature", signature); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	oidData              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidAttrContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+)
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     pkcs7IssuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type pkcs7Attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+func marshalPKCS7Attribute(oid asn1.ObjectIdentifier, value interface{}) ([]byte, error) {
+	encoded, err := asn1.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs7Attribute{
+		Type:  oid,
+		Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: encoded},
+	})
+}
+
+// SignDetachedPKCS7 produces the DER-encoded SignedData Apple expects in a
+// pass bundle: SHA-256, signed attributes, and the signer plus WWDR chain.
+func SignDetachedPKCS7(content []byte, signer *PassSigner) ([]byte, error) {
+	digest := sha256.Sum256(content)
+
+	var attrs [][]byte
+	for _, attr := range []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidAttrContentType, oidData},
+		{oidAttrSigningTime, time.Now().UTC()},
+		{oidAttrMessageDigest, digest[:]},
+	} {
+		encoded, err := marshalPKCS7Attribute(attr.oid, attr.value)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, encoded)
+	}
+	// DER requires SET OF members in ascending byte order.
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+	attrBytes := bytes.Join(attrs, nil)
+
+	signedAttrs, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: attrBytes})
+	if err != nil {
+		return nil, err
+	}
+	attrDigest := sha256.Sum256(signedAttrs)
+
+	var encryptionAlgorithm asn1.ObjectIdentifier
+	switch signer.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		encryptionAlgorithm = oidRSAEncryption
+	case *ecdsa.PrivateKey:
+		encryptionAlgorithm = oidECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("unsupported signing key type")
+	}
+
+	signature, err := signer.PrivateKey.Sign(rand.Reader, attrDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := append(append([]byte{}, signer.Certificate.Raw...), signer.Intermediate.Raw...)
+	sha256Algorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+
+	signedData := pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256Algorithm},
+		ContentInfo:      pkcs7ContentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos: []pkcs7SignerInfo{
+			{
+				Version: 1,
+				IssuerAndSerialNumber: pkcs7IssuerAndSerial{
+					Issuer:       asn1.RawValue{FullBytes: signer.Certificate.RawIssuer},
+					SerialNumber: signer.Certificate.SerialNumber,
+				},
+				DigestAlgorithm:           sha256Algorithm,
+				AuthenticatedAttributes:   asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrBytes},
+				DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: encryptionAlgorithm},
+				EncryptedDigest:           signature,
+			},
+		},
+	}
+
+	inner, err := asn1.Marshal(signedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// SignGoogleWalletJWT wraps a Google Wallet payload in the RS256 "save to
+// wallet" JWT and returns the link users open to add the pass.
+func SignGoogleWalletJWT(issuer *WalletIssuer, payload map[string]interface{}) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":     issuer.GoogleServiceAccountEmail,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"iat":     time.Now().Unix(),
+		"origins": []string{},
+		"payload": payload,
+	})
+
+	signed, err := token.SignedString(issuer.GoogleKey)
+	if err != nil {
+		return "", err
+	}
+	return GoogleWalletSaveURL + signed, nil
+}
+
+// PassImages renders the PNG images a pass bundle needs from an event banner.
+// Apple rejects passes without an icon, so a plain one is used when the event
+// has no banner or it can't be decoded.
+func PassImages(bannerPath string) map[string][]byte {
+	var banner image.Image
+	if bannerPath != "" {
+		if f, err := os.Open(bannerPath); err == nil {
+			banner, _, _ = image.Decode(f)
+			f.Close()
+		}
+	}
+
+	if banner == nil {
+		placeholder := image.NewRGBA(image.Rect(0, 0, 58, 58))
+		draw.Draw(placeholder, placeholder.Bounds(), &image.Uniform{C: color.RGBA{R: 30, G: 215, B: 96, A: 255}}, image.Point{}, draw.Src)
+		banner = placeholder
+	}
+
+	images := make(map[string][]byte)
+	for name, size := range map[string]image.Point{
+		"icon.png":     {X: 29, Y: 29},
+		"icon@2x.png":  {X: 58, Y: 58},
+		"strip.png":    {X: 375, Y: 98},
+		"strip@2x.png": {X: 750, Y: 196},
+	} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, resizeNearest(banner, size.X, size.Y)); err == nil {
+			images[name] = buf.Bytes()
+		}
+	}
+	return images
+}
+
+func resizeNearest(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/width
+			srcY := bounds.Min.Y + y*bounds.Dy()/height
+			dst.Set(x, y, src.At(srcX, srcY))
+		}
+	}
+	return dst
+}
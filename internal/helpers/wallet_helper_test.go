@@ -0,0 +1,289 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestPassSigner(t *testing.T, key crypto.Signer) *PassSigner {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test WWDR"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &PassSigner{Certificate: cert, PrivateKey: key, Intermediate: ca}
+}
+
+func readPKPass(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("pass is not a zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = content
+	}
+	return files
+}
+
+// verifyDetachedPKCS7 checks a SignedData produced by SignDetachedPKCS7
+// against content the way a pass reader would: the message digest attribute
+// must match, the signer must chain to the bundled intermediate, and the
+// signature over the attributes must verify with the signer's key.
+func verifyDetachedPKCS7(der, content []byte) error {
+	var outer pkcs7ContentInfo
+	if rest, err := asn1.Unmarshal(der, &outer); err != nil || len(rest) > 0 {
+		return fmt.Errorf("invalid ContentInfo: %v", err)
+	}
+	if !outer.ContentType.Equal(oidSignedData) {
+		return fmt.Errorf("content type = %v, want signedData", outer.ContentType)
+	}
+
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(outer.Content.Bytes, &signedData); err != nil {
+		return fmt.Errorf("invalid SignedData: %v", err)
+	}
+	if len(signedData.ContentInfo.Content.Bytes) != 0 {
+		return errors.New("signature is not detached")
+	}
+
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil || len(certs) != 2 {
+		return fmt.Errorf("expected signer and intermediate certificates, got %d (%v)", len(certs), err)
+	}
+	signerCert, intermediate := certs[0], certs[1]
+	if err := signerCert.CheckSignatureFrom(intermediate); err != nil {
+		return fmt.Errorf("signer does not chain to intermediate: %v", err)
+	}
+
+	if len(signedData.SignerInfos) != 1 {
+		return fmt.Errorf("signer infos = %d, want 1", len(signedData.SignerInfos))
+	}
+	info := signedData.SignerInfos[0]
+	if info.IssuerAndSerialNumber.SerialNumber.Cmp(signerCert.SerialNumber) != 0 {
+		return errors.New("signer info does not identify the signer certificate")
+	}
+
+	var attrs []pkcs7Attribute
+	rest := info.AuthenticatedAttributes.Bytes
+	for len(rest) > 0 {
+		var attr pkcs7Attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return fmt.Errorf("invalid attribute: %v", err)
+		}
+		attrs = append(attrs, attr)
+	}
+
+	var digest []byte
+	for _, attr := range attrs {
+		if attr.Type.Equal(oidAttrMessageDigest) {
+			if _, err := asn1.Unmarshal(attr.Value.Bytes, &digest); err != nil {
+				return fmt.Errorf("invalid message digest: %v", err)
+			}
+		}
+	}
+	want := sha256.Sum256(content)
+	if !bytes.Equal(digest, want[:]) {
+		return errors.New("message digest does not match the signed content")
+	}
+
+	// The signature covers the attributes re-encoded as a SET, not the
+	// implicit [0] they are stored under.
+	signedAttrs, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: info.AuthenticatedAttributes.Bytes})
+	if err != nil {
+		return err
+	}
+	attrDigest := sha256.Sum256(signedAttrs)
+
+	switch pub := signerCert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, attrDigest[:], info.EncryptedDigest); err != nil {
+			return fmt.Errorf("RSA signature does not verify: %v", err)
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, attrDigest[:], info.EncryptedDigest) {
+			return errors.New("ECDSA signature does not verify")
+		}
+	default:
+		return fmt.Errorf("unexpected public key type %T", pub)
+	}
+	return nil
+}
+
+func TestBuildPKPass(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, key := range map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecKey} {
+		t.Run(name, func(t *testing.T) {
+			signer := newTestPassSigner(t, key)
+			files := map[string][]byte{
+				"pass.json": []byte(`{"formatVersion":1}`),
+				"icon.png":  {0x89, 'P', 'N', 'G'},
+			}
+
+			data, err := BuildPKPass(files, signer)
+			if err != nil {
+				t.Fatalf("BuildPKPass: %v", err)
+			}
+			bundle := readPKPass(t, data)
+
+			var manifest map[string]string
+			if err := json.Unmarshal(bundle["manifest.json"], &manifest); err != nil {
+				t.Fatalf("invalid manifest: %v", err)
+			}
+			if len(manifest) != len(files) {
+				t.Fatalf("manifest lists %d files, want %d", len(manifest), len(files))
+			}
+			for name := range files {
+				sum := sha1.Sum(bundle[name])
+				if manifest[name] != hex.EncodeToString(sum[:]) {
+					t.Errorf("manifest hash for %s does not match the file", name)
+				}
+			}
+
+			if err := verifyDetachedPKCS7(bundle["signature"], bundle["manifest.json"]); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSignDetachedPKCS7RejectsTamperedContent(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := SignDetachedPKCS7([]byte("original"), newTestPassSigner(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyDetachedPKCS7(signature, []byte("tampered")); err == nil {
+		t.Fatal("signature verified against different content")
+	}
+}
+
+func TestSignGoogleWalletJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &WalletIssuer{
+		GoogleIssuerID:            "3388000000000000000",
+		GoogleServiceAccountEmail: "wallet@example.iam.gserviceaccount.com",
+		GoogleKey:                 key,
+	}
+	payload := map[string]interface{}{
+		"eventTicketObjects": []interface{}{map[string]interface{}{"id": "3388000000000000000.purchase"}},
+	}
+
+	link, err := SignGoogleWalletJWT(issuer, payload)
+	if err != nil {
+		t.Fatalf("SignGoogleWalletJWT: %v", err)
+	}
+	if !strings.HasPrefix(link, GoogleWalletSaveURL) {
+		t.Fatalf("link %q does not start with the save URL", link)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(strings.TrimPrefix(link, GoogleWalletSaveURL), claims, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithAudience("google"), jwt.WithIssuer(issuer.GoogleServiceAccountEmail))
+	if err != nil {
+		t.Fatalf("token does not verify with the service account key: %v", err)
+	}
+	if claims["typ"] != "savetowallet" {
+		t.Errorf("typ = %v, want savetowallet", claims["typ"])
+	}
+	objects, _ := claims["payload"].(map[string]interface{})["eventTicketObjects"].([]interface{})
+	if len(objects) != 1 {
+		t.Errorf("payload was not carried in the token: %v", claims["payload"])
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.Parse(strings.TrimPrefix(link, GoogleWalletSaveURL), func(token *jwt.Token) (interface{}, error) {
+		return &other.PublicKey, nil
+	})
+	if err == nil {
+		t.Fatal("token verified with an unrelated key")
+	}
+}
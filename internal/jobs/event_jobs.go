@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/farellandr/spoticket/internal/models"
+	"gorm.io/gorm"
+)
+
+func publishScheduledEvents(db *gorm.DB) error {
+	now := time.Now()
+	return db.Model(&models.Event{}).
+		Where("status = ? AND publish_at <= ?", models.EventStatusScheduled, now).
+		Updates(map[string]interface{}{
+			"status":       models.EventStatusPublished,
+			"published_at": now,
+		}).Error
+}
+
+func completeEndedEvents(db *gorm.DB) error {
+	return db.Model(&models.Event{}).
+		Where("status = ? AND end_time < ?", models.EventStatusPublished, time.Now()).
+		Update("status", models.EventStatusCompleted).Error
+}
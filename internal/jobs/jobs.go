@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/waitlist"
+	"gorm.io/gorm"
+)
+
+type job struct {
+	name string
+	run  func(db *gorm.DB) error
+}
+
+var jobs = []job{
+	{name: "publish scheduled events", run: publishScheduledEvents},
+	{name: "complete ended events", run: completeEndedEvents},
+	{name: "admit queue batches", run: admitQueueBatches},
+	{name: "expire queue admissions", run: expireQueueAdmissions},
+	{name: "expire waitlist offers", run: waitlist.ExpireOffers},
+	{name: "offer waitlisted tickets", run: waitlist.OfferAll},
+}
+
+// Start runs every background job once per interval for the lifetime of the
+// process.
+func Start(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			for _, j := range jobs {
+				if err := j.run(db); err != nil {
+					fmt.Printf("Error running job %q: %v\n", j.name, err)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
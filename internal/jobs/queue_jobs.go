@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/farellandr/spoticket/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// admitQueueBatches admits the next batch of every active waiting room whose
+// batch interval has passed.
+func admitQueueBatches(db *gorm.DB) error {
+	var rooms []models.WaitingRoom
+	if err := db.Where("is_active = ?", true).Find(&rooms).Error; err != nil {
+		return err
+	}
+
+	for _, room := range rooms {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&room, "id = ?", room.ID).Error; err != nil {
+				return err
+			}
+
+			now := time.Now()
+			interval := time.Duration(room.BatchIntervalSeconds) * time.Second
+			if room.LastAdmittedAt != nil && now.Sub(*room.LastAdmittedAt) < interval {
+				return nil
+			}
+
+			next := tx.Model(&models.QueueEntry{}).Select("id").
+				Where("waiting_room_id = ? AND status = ?", room.ID, models.QueueStatusWaiting).
+				Order("position").Limit(room.BatchSize)
+
+			result := tx.Model(&models.QueueEntry{}).Where("id IN (?)", next).Updates(map[string]interface{}{
+				"status":      models.QueueStatusAdmitted,
+				"admitted_at": now,
+				"expires_at":  now.Add(time.Duration(room.AdmissionMinutes) * time.Minute),
+			})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			return tx.Model(&room).Update("last_admitted_at", now).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expireQueueAdmissions(db *gorm.DB) error {
+	return db.Model(&models.QueueEntry{}).
+		Where("status = ? AND expires_at < ?", models.QueueStatusAdmitted, time.Now()).
+		Update("status", models.QueueStatusExpired).Error
+}
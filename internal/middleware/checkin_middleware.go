@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+func CheckInHubMiddleware(hub *helpers.CheckInHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("checkin_hub", hub)
+		c.Next()
+	}
+}
+
+func GetCheckInHub(c *gin.Context) *helpers.CheckInHub {
+	hub, exists := c.Get("checkin_hub")
+	if !exists {
+		return nil
+	}
+	return hub.(*helpers.CheckInHub)
+}
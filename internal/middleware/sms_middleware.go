@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+func SMSMiddleware(sender *helpers.SMSSender) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("sms_sender", sender)
+		c.Next()
+	}
+}
+
+func GetSMSSender(c *gin.Context) *helpers.SMSSender {
+	sender, exists := c.Get("sms_sender")
+	if !exists {
+		return nil
+	}
+	return sender.(*helpers.SMSSender)
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+func WalletMiddleware(issuer *helpers.WalletIssuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("wallet_issuer", issuer)
+		c.Next()
+	}
+}
+
+func GetWalletIssuer(c *gin.Context) *helpers.WalletIssuer {
+	issuer, exists := c.Get("wallet_issuer")
+	if !exists {
+		return nil
+	}
+	return issuer.(*helpers.WalletIssuer)
+}
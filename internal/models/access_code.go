@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccessCode unlocks hidden or locked ticket tiers, for example during a
+// presale. Unlike coupons it doesn't change the price.
+type AccessCode struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	EventID        uuid.UUID `gorm:"type:uuid;not null;index:idx_access_codes_event_code"`
+	Event          *Event    `gorm:"foreignKey:EventID"`
+	Code           string    `gorm:"not null;index:idx_access_codes_event_code"`
+	Tickets        []Ticket  `gorm:"many2many:access_code_tickets;"`
+	ValidFrom      *time.Time
+	ValidUntil     *time.Time
+	MaxUses        int   `gorm:"not null;default:0"`
+	MaxUsesPerUser int   `gorm:"not null;default:0"`
+	Uses           int64 `gorm:"->;-:migration"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
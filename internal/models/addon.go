@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AddonOrderItem is an add-on line of a checkout, priced when it was placed.
+type AddonOrderItem struct {
+	ID               uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PaymentID        uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Payment          *Payment        `gorm:"foreignKey:PaymentID;constraint:OnDelete:CASCADE"`
+	ProductVariantID uuid.UUID       `gorm:"type:uuid;not null;index"`
+	ProductVariant   *ProductVariant `gorm:"foreignKey:ProductVariantID"`
+	Quantity         int             `gorm:"not null"`
+	Price            int             `gorm:"not null"`
+	CreatedAt        time.Time
+}
+
+// AddonVoucher is one redeemable unit of a paid add-on.
+type AddonVoucher struct {
+	ID               uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PaymentID        uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Payment          *Payment        `gorm:"foreignKey:PaymentID;constraint:OnDelete:CASCADE"`
+	UserID           uuid.UUID       `gorm:"type:uuid;not null;index"`
+	User             *User           `gorm:"foreignKey:UserID"`
+	ProductVariantID uuid.UUID       `gorm:"type:uuid;not null;index"`
+	ProductVariant   *ProductVariant `gorm:"foreignKey:ProductVariantID"`
+	IsUsed           bool            `gorm:"not null;default:false"`
+	UsedAt           *time.Time
+	ScannedBy        *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Attendee struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PaymentID  uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Position   int        `gorm:"not null;default:0"`
+	PurchaseID *uuid.UUID `gorm:"type:uuid;uniqueIndex"`
+	Name       string     `gorm:"not null"`
+	Email      *string
+	IDNumber   *string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CheckInDirectionIn  = "in"
+	CheckInDirectionOut = "out"
+)
+
+type CheckIn struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PurchaseID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Purchase   *Purchase `gorm:"foreignKey:PurchaseID"`
+	Direction  string    `gorm:"not null;default:'in'"`
+	Gate       string    `gorm:"not null;default:'';index"`
+	ScannedBy  uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt  time.Time `gorm:"index"`
+}
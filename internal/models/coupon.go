@@ -7,24 +7,57 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	CouponDiscountPercentage  = "percentage"
+	CouponDiscountFixedAmount = "fixed_amount"
+	CouponDiscountFreeTicket  = "free_ticket"
+)
+
+// Coupon discounts checkouts. Organizer coupons only apply to the owner's
+// events, platform coupons to any event. A coupon with scopes only applies to
+// tickets matching at least one of its events, categories or tiers.
+//
+// Discount is a percentage for percentage coupons, capped at MaxDiscount when
+// set, and an amount for fixed amount coupons. Free ticket coupons take one
+// ticket off the order.
+//
+// Limit caps how many users can claim the coupon and MaxRedemptions, when set,
+// how many checkouts can use it. Unpaid checkouts hold a redemption until their
+// invoice expires.
 type Coupon struct {
-	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	Name        string    `gorm:"not null"`
-	Code        *string   `gorm:"unique"`
-	Limit       int       `gorm:"not null"`
-	Discount    int       `gorm:"not null"`
-	Description *string
-	ValidAt     time.Time `gorm:"not null"`
-	ExpiredAt   time.Time `gorm:"not null"`
-	Users       []User    `gorm:"many2many:user_coupons;"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	ID              uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Name            string    `gorm:"not null"`
+	Code            *string   `gorm:"unique"`
+	Limit           int       `gorm:"not null"`
+	ClaimCount      int       `gorm:"not null;default:0"`
+	MaxRedemptions  int       `gorm:"not null;default:0"`
+	RedemptionCount int       `gorm:"not null;default:0"`
+	MaxUsesPerUser  int       `gorm:"not null;default:1"`
+	Discount        int       `gorm:"not null"`
+	DiscountType    string    `gorm:"not null;default:'percentage'"`
+	MaxDiscount     int       `gorm:"not null;default:0"`
+	MinOrderAmount  int       `gorm:"not null;default:0"`
+	MinQuantity     int       `gorm:"not null;default:0"`
+	Stackable       bool      `gorm:"not null"`
+	Description     *string
+	ValidAt         time.Time  `gorm:"not null"`
+	ExpiredAt       time.Time  `gorm:"not null"`
+	UserID          *uuid.UUID `gorm:"type:uuid;index"`
+	User            *User      `gorm:"foreignKey:UserID"`
+	IsPlatform      bool       `gorm:"not null;default:false"`
+	Events          []Event    `gorm:"many2many:coupon_events;"`
+	Categories      []Category `gorm:"many2many:coupon_categories;"`
+	Tickets         []Ticket   `gorm:"many2many:coupon_tickets;"`
+	Users           []User     `gorm:"many2many:user_coupons;"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
 type UserCoupon struct {
-	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
-	CouponID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_user_coupons_user_coupon"`
+	CouponID  uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_user_coupons_user_coupon"`
+	UsedCount int       `gorm:"not null;default:0"`
 	IsUsed    bool      `gorm:"not null;default:false"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
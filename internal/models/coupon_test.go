@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB builds statements for Postgres without connecting to a database.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// insertedValue returns the value an INSERT statement writes to column.
+func insertedValue(t *testing.T, stmt *gorm.Statement, column string) interface{} {
+	t.Helper()
+
+	sql := stmt.SQL.String()
+	start := strings.Index(sql, "(")
+	end := strings.Index(sql, ")")
+	if start < 0 || end < start {
+		t.Fatalf("unexpected INSERT: %s", sql)
+	}
+	for i, name := range strings.Split(sql[start+1:end], ",") {
+		if strings.Trim(name, `" `) == column {
+			return stmt.Vars[i]
+		}
+	}
+	t.Fatalf("INSERT does not write %s: %s", column, sql)
+	return nil
+}
+
+func TestCreateNonStackableCoupon(t *testing.T) {
+	db := dryRunDB(t)
+
+	coupon := Coupon{Name: "Solo", Limit: 1, Discount: 10, Stackable: false}
+	stmt := db.Create(&coupon).Statement
+	if stmt.Error != nil {
+		t.Fatal(stmt.Error)
+	}
+
+	if got := insertedValue(t, stmt, "stackable"); got != false {
+		t.Errorf("stackable written as %v, want false", got)
+	}
+	if coupon.Stackable {
+		t.Error("create replaced stackable with a default")
+	}
+}
@@ -7,23 +7,64 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	EventStatusDraft     = "draft"
+	EventStatusScheduled = "scheduled"
+	EventStatusPublished = "published"
+	EventStatusCancelled = "cancelled"
+	EventStatusCompleted = "completed"
+)
+
 type Event struct {
-	ID          uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	Title       string     `gorm:"not null"`
-	Description string     `gorm:"not null"`
-	StartTime   time.Time  `gorm:"not null"`
-	EndTime     time.Time  `gorm:"not null"`
-	Province    string     `gorm:"not null"`
-	City        string     `gorm:"not null"`
-	District    string     `gorm:"not null"`
-	SubDistrict string     `gorm:"not null"`
-	Location    string     `gorm:"not null"`
-	UserID      uuid.UUID  `gorm:"type:uuid;not null;index"`
-	User        *User      `gorm:"foreignKey:UserID"`
-	Categories  []Category `gorm:"many2many:event_categories;"`
-	Tickets     []Ticket   `gorm:"foreignKey:EventID"`
-	BannerPath  string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	ID                uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Title             string     `gorm:"not null"`
+	Description       string     `gorm:"not null"`
+	StartTime         time.Time  `gorm:"not null"`
+	EndTime           time.Time  `gorm:"not null"`
+	Province          string     `gorm:"not null"`
+	City              string     `gorm:"not null"`
+	District          string     `gorm:"not null"`
+	SubDistrict       string     `gorm:"not null"`
+	Location          string     `gorm:"not null"`
+	Latitude          *float64   `gorm:"index:idx_events_coordinates"`
+	Longitude         *float64   `gorm:"index:idx_events_coordinates"`
+	VenueID           *uuid.UUID `gorm:"type:uuid;index"`
+	Venue             *Venue     `gorm:"foreignKey:VenueID"`
+	SeriesID          *uuid.UUID `gorm:"type:uuid;index"`
+	Timezone          string     `gorm:"not null;default:'Asia/Jakarta'"`
+	Status            string     `gorm:"not null;default:'published';index"`
+	PublishAt         *time.Time
+	PublishedAt       *time.Time
+	CancelledAt       *time.Time
+	UserID            uuid.UUID     `gorm:"type:uuid;not null;index"`
+	User              *User         `gorm:"foreignKey:UserID"`
+	Categories        []Category    `gorm:"many2many:event_categories;"`
+	Tickets           []Ticket      `gorm:"foreignKey:EventID"`
+	SeatSections      []SeatSection `gorm:"foreignKey:EventID"`
+	BannerPath        string
+	TransfersDisabled bool     `gorm:"not null;default:false"`
+	ResaleEnabled     bool     `gorm:"not null;default:false"`
+	ResaleMaxMarkup   int      `gorm:"not null;default:0"`
+	Distance          *float64 `gorm:"->;-:migration"`
+	Rank              *float64 `gorm:"->;-:migration"`
+	Highlight         *string  `gorm:"->;-:migration"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+}
+
+// DefaultEventTimezone is where events without a recorded timezone are held.
+const DefaultEventTimezone = "Asia/Jakarta"
+
+// TimeLocation returns the location of the event's timezone, falling back to
+// WIB when the zone is unknown.
+func (e *Event) TimeLocation() *time.Location {
+	name := e.Timezone
+	if name == "" {
+		name = DefaultEventTimezone
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return time.FixedZone("WIB", 7*60*60)
 }
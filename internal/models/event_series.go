@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EventSeries struct {
+	ID              uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Title           string    `gorm:"not null"`
+	Description     string    `gorm:"not null"`
+	RRule           string    `gorm:"column:rrule;not null"`
+	FirstStartTime  time.Time `gorm:"not null"`
+	DurationMinutes int       `gorm:"not null"`
+	Province        string    `gorm:"not null"`
+	City            string    `gorm:"not null"`
+	District        string    `gorm:"not null"`
+	SubDistrict     string    `gorm:"not null"`
+	Location        string    `gorm:"not null"`
+	Latitude        *float64
+	Longitude       *float64
+	VenueID         *uuid.UUID             `gorm:"type:uuid;index"`
+	Venue           *Venue                 `gorm:"foreignKey:VenueID"`
+	UserID          uuid.UUID              `gorm:"type:uuid;not null;index"`
+	User            *User                  `gorm:"foreignKey:UserID"`
+	Categories      []Category             `gorm:"many2many:event_series_categories;"`
+	TicketTemplates []SeriesTicketTemplate `gorm:"foreignKey:SeriesID;constraint:OnDelete:CASCADE"`
+	Events          []Event                `gorm:"foreignKey:SeriesID"`
+	BannerPath      string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
+
+type SeriesTicketTemplate struct {
+	ID                     uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	SeriesID               uuid.UUID `gorm:"type:uuid;not null;index"`
+	Type                   string    `gorm:"not null"`
+	Price                  int       `gorm:"not null"`
+	Limit                  int       `gorm:"not null"`
+	EntryPolicy            string    `gorm:"not null;default:'single'"`
+	MaxEntries             int       `gorm:"not null;default:0"`
+	RequireAttendeeDetails bool      `gorm:"not null;default:false"`
+	MinPerOrder            int       `gorm:"not null;default:0"`
+	MaxPerOrder            int       `gorm:"not null;default:0"`
+	MaxPerUser             int       `gorm:"not null;default:0"`
+	RequireVerifiedPhone   bool      `gorm:"not null;default:false"`
+	OnePerPhone            bool      `gorm:"not null;default:false"`
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+}
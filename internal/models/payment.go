@@ -8,17 +8,25 @@ import (
 )
 
 type Payment struct {
-	ID            uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	Amount        int        `gorm:"not null"`
-	Method        string     `gorm:"not null"`
-	Status        string     `gorm:"not null;default:'pending'"`
-	TransactionID string     `gorm:"not null"`
-	UserID        uuid.UUID  `gorm:"type:uuid;not null;index"`
-	User          *User      `gorm:"foreignKey:UserID"`
-	CouponID      *uuid.UUID `gorm:"type:uuid"`
-	Coupon        *Coupon    `gorm:"foreignKey:CouponID"`
-	Purchase      *Purchase  `gorm:"foreignKey:PaymentID"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeletedAt     gorm.DeletedAt `gorm:"index"`
+	ID              uuid.UUID           `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Amount          int                 `gorm:"not null"`
+	AdminFee        int                 `gorm:"not null;default:0"`
+	Method          string              `gorm:"not null"`
+	Status          string              `gorm:"not null;default:'pending'"`
+	TransactionID   string              `gorm:"not null"`
+	UserID          uuid.UUID           `gorm:"type:uuid;not null;index"`
+	User            *User               `gorm:"foreignKey:UserID"`
+	TicketID        *uuid.UUID          `gorm:"type:uuid;index"`
+	Quantity        int                 `gorm:"not null;default:0"`
+	CouponID        *uuid.UUID          `gorm:"type:uuid"`
+	Coupon          *Coupon             `gorm:"foreignKey:CouponID"`
+	AccessCodeID    *uuid.UUID          `gorm:"type:uuid;index"`
+	ResaleListingID *uuid.UUID          `gorm:"type:uuid;index"`
+	PricingRules    AppliedPricingRules `gorm:"type:jsonb;not null;default:'[]'"`
+	Purchase        *Purchase           `gorm:"foreignKey:PaymentID"`
+	Attendees       []Attendee          `gorm:"foreignKey:PaymentID"`
+	AddonItems      []AddonOrderItem    `gorm:"foreignKey:PaymentID"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PhoneVerification struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	User        *User     `gorm:"foreignKey:UserID"`
+	PhoneNumber string    `gorm:"not null"`
+	CodeHash    string    `gorm:"not null"`
+	Attempts    int       `gorm:"not null;default:0"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	VerifiedAt  *time.Time
+	CreatedAt   time.Time
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PricingRuleGroupDiscount = "group_discount"
+	PricingRuleBuyXGetY      = "buy_x_get_y"
+	PricingRuleDemandStep    = "demand_step"
+)
+
+// PricingRule adjusts the price of a ticket tier at checkout. Which fields are
+// used depends on the rule type:
+//   - group_discount takes PercentOff off orders of at least MinQuantity.
+//   - buy_x_get_y gives FreeQuantity tickets free for every BuyQuantity bought.
+//   - demand_step sets the unit price to Price once SoldThreshold are sold.
+type PricingRule struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	TicketID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	Ticket        *Ticket   `gorm:"foreignKey:TicketID"`
+	Type          string    `gorm:"not null"`
+	Name          string    `gorm:"not null"`
+	IsActive      bool      `gorm:"not null"`
+	MinQuantity   int       `gorm:"not null;default:0"`
+	PercentOff    int       `gorm:"not null;default:0"`
+	BuyQuantity   int       `gorm:"not null;default:0"`
+	FreeQuantity  int       `gorm:"not null;default:0"`
+	SoldThreshold int       `gorm:"not null;default:0"`
+	Price         int       `gorm:"not null;default:0"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+// AppliedPricingRule records how much a rule changed an order's total.
+// Discounts are negative.
+type AppliedPricingRule struct {
+	RuleID uuid.UUID `json:"rule_id"`
+	Type   string    `json:"type"`
+	Name   string    `json:"name"`
+	Amount int       `json:"amount"`
+}
+
+// AppliedPricingRules is stored as JSON so payments keep the rules as they
+// were at checkout.
+type AppliedPricingRules []AppliedPricingRule
+
+func (r AppliedPricingRules) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (r *AppliedPricingRules) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type %T for AppliedPricingRules", value)
+	}
+	return json.Unmarshal(data, r)
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateInactivePricingRule(t *testing.T) {
+	db := dryRunDB(t)
+
+	rule := PricingRule{TicketID: uuid.New(), Type: PricingRuleGroupDiscount, Name: "Group", IsActive: false, MinQuantity: 5, PercentOff: 10}
+	stmt := db.Create(&rule).Statement
+	if stmt.Error != nil {
+		t.Fatal(stmt.Error)
+	}
+
+	if got := insertedValue(t, stmt, "is_active"); got != false {
+		t.Errorf("is_active written as %v, want false", got)
+	}
+	if rule.IsActive {
+		t.Error("create replaced is_active with a default")
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ProductTypeParking     = "parking"
+	ProductTypeMerchandise = "merchandise"
+	ProductTypeFood        = "food"
+)
+
+// Product is a non-admission add-on sold alongside an event's tickets.
+type Product struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	EventID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Event       *Event    `gorm:"foreignKey:EventID"`
+	Type        string    `gorm:"not null"`
+	Name        string    `gorm:"not null"`
+	Description string
+	Price       int              `gorm:"not null"`
+	IsActive    bool             `gorm:"not null"`
+	Variants    []ProductVariant `gorm:"foreignKey:ProductID"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// ProductVariant holds the inventory of a product, such as one merchandise
+// size. Products without options have a single variant.
+type ProductVariant struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Product   *Product  `gorm:"foreignKey:ProductID"`
+	Name      string    `gorm:"not null;default:''"`
+	Limit     int       `gorm:"not null"`
+	Available int       `gorm:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateInactiveProduct(t *testing.T) {
+	db := dryRunDB(t)
+
+	product := Product{EventID: uuid.New(), Type: ProductTypeParking, Name: "Parking", Price: 20000, IsActive: false}
+	stmt := db.Create(&product).Statement
+	if stmt.Error != nil {
+		t.Fatal(stmt.Error)
+	}
+
+	if got := insertedValue(t, stmt, "is_active"); got != false {
+		t.Errorf("is_active written as %v, want false", got)
+	}
+	if product.IsActive {
+		t.Error("create replaced is_active with a default")
+	}
+}
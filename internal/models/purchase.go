@@ -8,15 +8,22 @@ import (
 )
 
 type Purchase struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	IsUsed    bool      `gorm:"not null;default:false"`
-	TicketID  uuid.UUID `gorm:"type:uuid;not null;index"`
-	Ticket    *Ticket   `gorm:"foreignKey:TicketID"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
-	User      *User     `gorm:"foreignKey:UserID"`
-	PaymentID uuid.UUID `gorm:"type:uuid;not null;index"`
-	Payment   *Payment  `gorm:"foreignKey:PaymentID;constraint:OnDelete:CASCADE"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID         uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	IsUsed     bool       `gorm:"not null;default:false"`
+	EntryCount int        `gorm:"not null;default:0"`
+	IsInside   bool       `gorm:"not null;default:false"`
+	TicketID   uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Ticket     *Ticket    `gorm:"foreignKey:TicketID"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	User       *User      `gorm:"foreignKey:UserID"`
+	PaymentID  uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Payment    *Payment   `gorm:"foreignKey:PaymentID;constraint:OnDelete:CASCADE"`
+	SeatID     *uuid.UUID `gorm:"type:uuid"`
+	Seat       *Seat      `gorm:"foreignKey:SeatID"`
+	Attendee   *Attendee  `gorm:"foreignKey:PurchaseID"`
+	CheckIns   []CheckIn  `gorm:"foreignKey:PurchaseID"`
+	Transfers  []Transfer `gorm:"foreignKey:PurchaseID"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ResaleStatusListed    = "listed"
+	ResaleStatusSold      = "sold"
+	ResaleStatusCancelled = "cancelled"
+)
+
+type ResaleListing struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PurchaseID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Purchase      *Purchase  `gorm:"foreignKey:PurchaseID"`
+	SellerID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Seller        *User      `gorm:"foreignKey:SellerID"`
+	BuyerID       *uuid.UUID `gorm:"type:uuid;index"`
+	Buyer         *User      `gorm:"foreignKey:BuyerID"`
+	Price         int        `gorm:"not null"`
+	Status        string     `gorm:"not null;default:'listed';index"`
+	TransactionID *string    `gorm:"index"`
+	ReservedUntil *time.Time
+	SoldAt        *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SeatSection struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	EventID   *uuid.UUID `gorm:"type:uuid;index"`
+	VenueID   *uuid.UUID `gorm:"type:uuid;index"`
+	Name      string     `gorm:"not null"`
+	Seats     []Seat     `gorm:"foreignKey:SectionID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Seat struct {
+	ID            uuid.UUID    `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	SectionID     uuid.UUID    `gorm:"type:uuid;not null;index"`
+	Section       *SeatSection `gorm:"foreignKey:SectionID"`
+	Row           string       `gorm:"not null"`
+	Number        int          `gorm:"not null"`
+	IsAccessible  bool         `gorm:"not null;default:false"`
+	TicketID      *uuid.UUID   `gorm:"type:uuid;index"`
+	PurchaseID    *uuid.UUID   `gorm:"type:uuid;uniqueIndex"`
+	HeldByUserID  *uuid.UUID   `gorm:"type:uuid"`
+	HeldUntil     *time.Time
+	HoldReference *string `gorm:"index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -7,15 +7,49 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	EntryPolicySingle    = "single"
+	EntryPolicyMulti     = "multi"
+	EntryPolicyDaily     = "daily"
+	EntryPolicyUnlimited = "unlimited"
+)
+
+const (
+	TicketVisibilityPublic = "public"
+	TicketVisibilityHidden = "hidden"
+	TicketVisibilityLocked = "locked"
+)
+
+const (
+	TicketSaleUpcoming = "upcoming"
+	TicketSaleOnSale   = "on_sale"
+	TicketSaleSoldOut  = "sold_out"
+	TicketSaleEnded    = "ended"
+)
+
 type Ticket struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	Type      string    `gorm:"not null"`
-	Price     int       `gorm:"not null"`
-	Limit     int
-	EventID   uuid.UUID  `gorm:"type:uuid;not null;index"`
-	Event     *Event     `gorm:"foreignKey:EventID"`
-	Purchases []Purchase `gorm:"foreignKey:TicketID"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID                     uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Type                   string    `gorm:"not null"`
+	Price                  int       `gorm:"not null"`
+	Limit                  int
+	EntryPolicy            string `gorm:"not null;default:'single'"`
+	MaxEntries             int    `gorm:"not null;default:0"`
+	RequireAttendeeDetails bool   `gorm:"not null;default:false"`
+	Visibility             string `gorm:"not null;default:'public'"`
+	MinPerOrder            int    `gorm:"not null;default:0"`
+	MaxPerOrder            int    `gorm:"not null;default:0"`
+	MaxPerUser             int    `gorm:"not null;default:0"`
+	RequireVerifiedPhone   bool   `gorm:"not null;default:false"`
+	OnePerPhone            bool   `gorm:"not null;default:false"`
+	SalesStart             *time.Time
+	SalesEnd               *time.Time
+	OpensAfterTicketID     *uuid.UUID `gorm:"type:uuid"`
+	SaleStatus             string     `gorm:"-"`
+	PendingQuantity        int        `gorm:"-" json:"-"`
+	EventID                uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Event                  *Event     `gorm:"foreignKey:EventID"`
+	Purchases              []Purchase `gorm:"foreignKey:TicketID"`
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	DeletedAt              gorm.DeletedAt `gorm:"index"`
 }
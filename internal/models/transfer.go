@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusAccepted  = "accepted"
+	TransferStatusDeclined  = "declined"
+	TransferStatusCancelled = "cancelled"
+)
+
+type Transfer struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	PurchaseID  uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Purchase    *Purchase  `gorm:"foreignKey:PurchaseID"`
+	FromUserID  uuid.UUID  `gorm:"type:uuid;not null;index"`
+	FromUser    *User      `gorm:"foreignKey:FromUserID"`
+	ToEmail     string     `gorm:"not null;index"`
+	ToUserID    *uuid.UUID `gorm:"type:uuid;index"`
+	ToUser      *User      `gorm:"foreignKey:ToUserID"`
+	Status      string     `gorm:"not null;default:'pending'"`
+	RespondedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -8,22 +8,23 @@ import (
 )
 
 type User struct {
-	ID             uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	Name           string     `gorm:"not null"`
-	Email          string     `gorm:"unique;not null"`
-	Password       string     `gorm:"not null"`
-	PhoneNumber    string     `gorm:"not null"`
-	RoleID         uuid.UUID  `gorm:"type:uuid;not null;index"`
-	Role           *Role      `gorm:"foreignKey:RoleID"`
-	Events         []Event    `gorm:"foreignKey:UserID"`
-	Purchases      []Purchase `gorm:"foreignKey:UserID"`
-	Payments       []Payment  `gorm:"foreignKey:UserID"`
-	Coupons        []Coupon   `gorm:"many2many:user_coupons;"`
-	AccountNumber  *string
-	AccountChannel *string
-	AccountName    *string
-	ProfilePicture *string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	ID              uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Name            string    `gorm:"not null"`
+	Email           string    `gorm:"unique;not null"`
+	Password        string    `gorm:"not null" json:"-"`
+	PhoneNumber     string    `gorm:"not null;index" json:"-"`
+	PhoneVerifiedAt *time.Time
+	RoleID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Role            *Role      `gorm:"foreignKey:RoleID"`
+	Events          []Event    `gorm:"foreignKey:UserID"`
+	Purchases       []Purchase `gorm:"foreignKey:UserID"`
+	Payments        []Payment  `gorm:"foreignKey:UserID"`
+	Coupons         []Coupon   `gorm:"many2many:user_coupons;"`
+	AccountNumber   *string    `json:"-"`
+	AccountChannel  *string
+	AccountName     *string
+	ProfilePicture  *string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUserJSONHidesPrivateFields(t *testing.T) {
+	account := "1234567890"
+	user := User{Name: "Rina", Email: "rina@example.com", Password: "$2a$10$hash", PhoneNumber: "081234567890", AccountNumber: &account}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hidden := range []string{"Password", "PhoneNumber", "AccountNumber"} {
+		if _, ok := fields[hidden]; ok {
+			t.Errorf("%s is serialized", hidden)
+		}
+	}
+	if fields["Email"] != user.Email {
+		t.Errorf("Email = %v, want %s", fields["Email"], user.Email)
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Venue struct {
+	ID                   uuid.UUID     `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Name                 string        `gorm:"not null"`
+	Address              string        `gorm:"not null"`
+	Province             string        `gorm:"not null"`
+	City                 string        `gorm:"not null"`
+	District             string        `gorm:"not null"`
+	SubDistrict          string        `gorm:"not null"`
+	Latitude             *float64      `gorm:"index:idx_venues_coordinates"`
+	Longitude            *float64      `gorm:"index:idx_venues_coordinates"`
+	Capacity             int           `gorm:"not null;default:0"`
+	WheelchairAccessible bool          `gorm:"not null;default:false"`
+	AccessibilityNotes   string        `gorm:"type:text"`
+	UserID               uuid.UUID     `gorm:"type:uuid;not null;index"`
+	User                 *User         `gorm:"foreignKey:UserID"`
+	Photos               []VenuePhoto  `gorm:"foreignKey:VenueID;constraint:OnDelete:CASCADE"`
+	SeatSections         []SeatSection `gorm:"foreignKey:VenueID"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	DeletedAt            gorm.DeletedAt `gorm:"index"`
+}
+
+type VenuePhoto struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	VenueID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	Path      string    `gorm:"not null"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	QueueStatusWaiting  = "waiting"
+	QueueStatusAdmitted = "admitted"
+	QueueStatusExpired  = "expired"
+	QueueStatusUsed     = "used"
+)
+
+type WaitingRoom struct {
+	ID                   uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	EventID              uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	Event                *Event    `gorm:"foreignKey:EventID"`
+	IsActive             bool      `gorm:"not null;default:false"`
+	BatchSize            int       `gorm:"not null"`
+	BatchIntervalSeconds int       `gorm:"not null"`
+	AdmissionMinutes     int       `gorm:"not null"`
+	LastAdmittedAt       *time.Time
+	Entries              []QueueEntry `gorm:"foreignKey:WaitingRoomID;constraint:OnDelete:CASCADE"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+type QueueEntry struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	WaitingRoomID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_queue_entries_room_user;index:idx_queue_entries_room_position"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_queue_entries_room_user"`
+	User          *User     `gorm:"foreignKey:UserID"`
+	Position      int64     `gorm:"not null;index:idx_queue_entries_room_position"`
+	Status        string    `gorm:"not null;default:'waiting';index"`
+	AdmittedAt    *time.Time
+	ExpiresAt     *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
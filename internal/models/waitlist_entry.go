@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusOffered   = "offered"
+	WaitlistStatusConverted = "converted"
+	WaitlistStatusExpired   = "expired"
+	WaitlistStatusCancelled = "cancelled"
+)
+
+type WaitlistEntry struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	TicketID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	Ticket         *Ticket   `gorm:"foreignKey:TicketID"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	User           *User     `gorm:"foreignKey:UserID"`
+	Quantity       int       `gorm:"not null"`
+	Status         string    `gorm:"not null;default:'waiting';index"`
+	OfferedAt      *time.Time
+	OfferExpiresAt *time.Time
+	PaymentID      *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
@@ -0,0 +1,101 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/farellandr/spoticket/internal/models"
+)
+
+var (
+	ErrBelowMinQuantity = errors.New("order does not meet the coupon's minimum quantity")
+	ErrBelowMinOrder    = errors.New("order does not meet the coupon's minimum spend")
+	ErrNotStackable     = errors.New("coupon can't be combined with other discounts")
+
+	ErrPercentOutOfRange     = errors.New("percentage discount must be between 1 and 100")
+	ErrNegativeMaxDiscount   = errors.New("maximum discount is negative")
+	ErrFixedAmountTooSmall   = errors.New("fixed discount must be at least 1")
+	ErrMaxDiscountNotPercent = errors.New("maximum discount only applies to percentage coupons")
+	ErrFreeTicketAmount      = errors.New("free ticket coupons don't take a discount amount")
+	ErrUnknownDiscountType   = errors.New("unknown discount type")
+	ErrNegativeMinimums      = errors.New("coupon minimums are negative")
+	ErrExpiryBeforeValidity  = errors.New("coupon expires before it becomes valid")
+)
+
+// ValidateCoupon rejects coupon configurations that can't produce a sensible
+// discount.
+func ValidateCoupon(coupon *models.Coupon) error {
+	switch coupon.DiscountType {
+	case models.CouponDiscountPercentage:
+		if coupon.Discount < 1 || coupon.Discount > 100 {
+			return ErrPercentOutOfRange
+		}
+		if coupon.MaxDiscount < 0 {
+			return ErrNegativeMaxDiscount
+		}
+	case models.CouponDiscountFixedAmount:
+		if coupon.Discount < 1 {
+			return ErrFixedAmountTooSmall
+		}
+		if coupon.MaxDiscount != 0 {
+			return ErrMaxDiscountNotPercent
+		}
+	case models.CouponDiscountFreeTicket:
+		if coupon.Discount != 0 || coupon.MaxDiscount != 0 {
+			return ErrFreeTicketAmount
+		}
+	default:
+		return ErrUnknownDiscountType
+	}
+
+	if coupon.MinOrderAmount < 0 || coupon.MinQuantity < 0 {
+		return ErrNegativeMinimums
+	}
+	if !coupon.ExpiredAt.After(coupon.ValidAt) {
+		return ErrExpiryBeforeValidity
+	}
+	return nil
+}
+
+// CouponDiscount returns how much the coupon takes off a quote. The discount
+// never exceeds the quote's total. Coupons that don't stack can't be used on
+// quotes that pricing rules already discounted.
+func CouponDiscount(coupon *models.Coupon, quote Quote) (int, error) {
+	if quote.Quantity < coupon.MinQuantity {
+		return 0, ErrBelowMinQuantity
+	}
+	if quote.Total < coupon.MinOrderAmount {
+		return 0, ErrBelowMinOrder
+	}
+	if !coupon.Stackable {
+		for _, rule := range quote.Applied {
+			if rule.Amount < 0 {
+				return 0, ErrNotStackable
+			}
+		}
+	}
+
+	var discount int
+	switch coupon.DiscountType {
+	case models.CouponDiscountFixedAmount:
+		discount = coupon.Discount
+	case models.CouponDiscountFreeTicket:
+		discount = quote.UnitPrice
+	default:
+		discount = quote.Total * coupon.Discount / 100
+		if coupon.MaxDiscount > 0 {
+			discount = min(discount, coupon.MaxDiscount)
+		}
+	}
+	return min(max(discount, 0), quote.Total), nil
+}
+
+// CouponLabel describes the coupon on invoices.
+func CouponLabel(coupon *models.Coupon) string {
+	switch coupon.DiscountType {
+	case models.CouponDiscountPercentage:
+		return fmt.Sprintf("Coupon %s (%d%%)", coupon.Name, coupon.Discount)
+	default:
+		return fmt.Sprintf("Coupon %s", coupon.Name)
+	}
+}
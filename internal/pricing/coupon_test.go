@@ -0,0 +1,109 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/farellandr/spoticket/internal/models"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	percent := func(discount, maxDiscount int) models.Coupon {
+		return models.Coupon{DiscountType: models.CouponDiscountPercentage, Discount: discount, MaxDiscount: maxDiscount, Stackable: true}
+	}
+	fixed := models.Coupon{DiscountType: models.CouponDiscountFixedAmount, Discount: 5000, Stackable: true}
+	free := models.Coupon{DiscountType: models.CouponDiscountFreeTicket, Stackable: true}
+	minOrder := percent(10, 0)
+	minOrder.MinOrderAmount = 1000
+	minQuantity := fixed
+	minQuantity.MinQuantity = 2
+	nonStackable := percent(10, 0)
+	nonStackable.Stackable = false
+
+	group := models.PricingRule{Name: "Group", Type: models.PricingRuleGroupDiscount, IsActive: true, MinQuantity: 2, PercentOff: 10}
+	step := models.PricingRule{Name: "Step", Type: models.PricingRuleDemandStep, IsActive: true, SoldThreshold: 1, Price: 1500}
+
+	tests := []struct {
+		name     string
+		coupon   models.Coupon
+		quote    Quote
+		discount int
+		err      error
+	}{
+		{name: "percentage", coupon: percent(20, 0), quote: Evaluate(1000, 3, 0, nil), discount: 600},
+		{name: "percentage under cap", coupon: percent(20, 1000), quote: Evaluate(1000, 3, 0, nil), discount: 600},
+		{name: "percentage capped", coupon: percent(20, 500), quote: Evaluate(1000, 3, 0, nil), discount: 500},
+		{name: "full percentage", coupon: percent(100, 0), quote: Evaluate(1000, 3, 0, nil), discount: 3000},
+		{name: "fixed below total", coupon: fixed, quote: Evaluate(3000, 2, 0, nil), discount: 5000},
+		{name: "fixed above total", coupon: fixed, quote: Evaluate(3000, 1, 0, nil), discount: 3000},
+		{name: "free ticket on one ticket", coupon: free, quote: Evaluate(1000, 1, 0, nil), discount: 1000},
+		{name: "free ticket on several tickets", coupon: free, quote: Evaluate(1000, 3, 0, nil), discount: 1000},
+		{name: "free ticket at step price", coupon: free, quote: Evaluate(1000, 2, 1, []models.PricingRule{step}), discount: 1500},
+		{name: "free ticket after group discount", coupon: free, quote: Quote{UnitPrice: 1000, Quantity: 1, Total: 800}, discount: 800},
+		{name: "at minimum order", coupon: minOrder, quote: Evaluate(500, 2, 0, nil), discount: 100},
+		{name: "below minimum order", coupon: minOrder, quote: Evaluate(999, 1, 0, nil), err: ErrBelowMinOrder},
+		{name: "minimum order after discounts", coupon: minOrder, quote: Evaluate(500, 2, 0, []models.PricingRule{group}), err: ErrBelowMinOrder},
+		{name: "at minimum quantity", coupon: minQuantity, quote: Evaluate(5000, 2, 0, nil), discount: 5000},
+		{name: "below minimum quantity", coupon: minQuantity, quote: Evaluate(5000, 1, 0, nil), err: ErrBelowMinQuantity},
+		{name: "non-stackable without rules", coupon: nonStackable, quote: Evaluate(1000, 2, 0, nil), discount: 200},
+		{name: "non-stackable with discount rule", coupon: nonStackable, quote: Evaluate(1000, 2, 0, []models.PricingRule{group}), err: ErrNotStackable},
+		{name: "non-stackable with demand step", coupon: nonStackable, quote: Evaluate(1000, 2, 1, []models.PricingRule{step}), discount: 300},
+		{name: "stackable with discount rule", coupon: percent(10, 0), quote: Evaluate(1000, 2, 0, []models.PricingRule{group}), discount: 180},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discount, err := CouponDiscount(&tt.coupon, tt.quote)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if discount != tt.discount {
+				t.Errorf("discount = %d, want %d", discount, tt.discount)
+			}
+		})
+	}
+}
+
+func TestValidateCoupon(t *testing.T) {
+	validAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	coupon := func(discountType string, discount, maxDiscount int) models.Coupon {
+		return models.Coupon{
+			DiscountType: discountType,
+			Discount:     discount,
+			MaxDiscount:  maxDiscount,
+			ValidAt:      validAt,
+			ExpiredAt:    validAt.Add(24 * time.Hour),
+		}
+	}
+	negativeMinimum := coupon(models.CouponDiscountFixedAmount, 1000, 0)
+	negativeMinimum.MinOrderAmount = -1
+	expired := coupon(models.CouponDiscountFixedAmount, 1000, 0)
+	expired.ExpiredAt = validAt
+
+	tests := []struct {
+		name   string
+		coupon models.Coupon
+		err    error
+	}{
+		{name: "percentage", coupon: coupon(models.CouponDiscountPercentage, 100, 5000)},
+		{name: "percentage zero", coupon: coupon(models.CouponDiscountPercentage, 0, 0), err: ErrPercentOutOfRange},
+		{name: "percentage over 100", coupon: coupon(models.CouponDiscountPercentage, 101, 0), err: ErrPercentOutOfRange},
+		{name: "negative cap", coupon: coupon(models.CouponDiscountPercentage, 10, -1), err: ErrNegativeMaxDiscount},
+		{name: "fixed", coupon: coupon(models.CouponDiscountFixedAmount, 1, 0)},
+		{name: "fixed zero", coupon: coupon(models.CouponDiscountFixedAmount, 0, 0), err: ErrFixedAmountTooSmall},
+		{name: "fixed with cap", coupon: coupon(models.CouponDiscountFixedAmount, 1000, 500), err: ErrMaxDiscountNotPercent},
+		{name: "free ticket", coupon: coupon(models.CouponDiscountFreeTicket, 0, 0)},
+		{name: "free ticket with amount", coupon: coupon(models.CouponDiscountFreeTicket, 10, 0), err: ErrFreeTicketAmount},
+		{name: "unknown type", coupon: coupon("bogo", 10, 0), err: ErrUnknownDiscountType},
+		{name: "negative minimum", coupon: negativeMinimum, err: ErrNegativeMinimums},
+		{name: "expiry at validity", coupon: expired, err: ErrExpiryBeforeValidity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCoupon(&tt.coupon); err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
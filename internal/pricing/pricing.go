@@ -0,0 +1,90 @@
+package pricing
+
+import (
+	"github.com/farellandr/spoticket/internal/models"
+)
+
+// Quote is the price of an order of one ticket tier after its pricing rules.
+type Quote struct {
+	UnitPrice int
+	Quantity  int
+	Subtotal  int
+	Applied   models.AppliedPricingRules
+	Total     int
+}
+
+// Evaluate prices quantity tickets at basePrice when sold tickets of the tier
+// have already been sold. The highest demand step reached sets the unit
+// price, then the best buy-x-get-y rule and the best group discount are
+// applied, in that order. Inactive rules are ignored.
+func Evaluate(basePrice, quantity, sold int, rules []models.PricingRule) Quote {
+	quote := Quote{
+		UnitPrice: basePrice,
+		Quantity:  quantity,
+		Subtotal:  basePrice * quantity,
+	}
+
+	var step, bundle, group *models.PricingRule
+	bestFree := 0
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.IsActive {
+			continue
+		}
+
+		switch rule.Type {
+		case models.PricingRuleDemandStep:
+			if sold >= rule.SoldThreshold && (step == nil || rule.SoldThreshold > step.SoldThreshold) {
+				step = rule
+			}
+		case models.PricingRuleBuyXGetY:
+			if free := freeTickets(rule, quantity); free > bestFree {
+				bundle, bestFree = rule, free
+			}
+		case models.PricingRuleGroupDiscount:
+			if quantity >= rule.MinQuantity && (group == nil || rule.PercentOff > group.PercentOff) {
+				group = rule
+			}
+		}
+	}
+
+	total := quote.Subtotal
+	if step != nil && step.Price != basePrice {
+		quote.UnitPrice = step.Price
+		amount := (step.Price - basePrice) * quantity
+		quote.Applied = append(quote.Applied, applied(step, amount))
+		total += amount
+	}
+	if bundle != nil && quote.UnitPrice > 0 {
+		amount := -bestFree * quote.UnitPrice
+		quote.Applied = append(quote.Applied, applied(bundle, amount))
+		total += amount
+	}
+	if group != nil {
+		amount := -total * group.PercentOff / 100
+		if amount != 0 {
+			quote.Applied = append(quote.Applied, applied(group, amount))
+			total += amount
+		}
+	}
+
+	quote.Total = total
+	return quote
+}
+
+func freeTickets(rule *models.PricingRule, quantity int) int {
+	set := rule.BuyQuantity + rule.FreeQuantity
+	if rule.BuyQuantity < 1 || rule.FreeQuantity < 1 {
+		return 0
+	}
+	return quantity / set * rule.FreeQuantity
+}
+
+func applied(rule *models.PricingRule, amount int) models.AppliedPricingRule {
+	return models.AppliedPricingRule{
+		RuleID: rule.ID,
+		Type:   rule.Type,
+		Name:   rule.Name,
+		Amount: amount,
+	}
+}
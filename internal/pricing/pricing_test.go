@@ -0,0 +1,68 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/farellandr/spoticket/internal/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	group := models.PricingRule{Name: "Group", Type: models.PricingRuleGroupDiscount, IsActive: true, MinQuantity: 5, PercentOff: 10}
+	bundle := models.PricingRule{Name: "Bundle", Type: models.PricingRuleBuyXGetY, IsActive: true, BuyQuantity: 2, FreeQuantity: 1}
+	step := models.PricingRule{Name: "Step", Type: models.PricingRuleDemandStep, IsActive: true, SoldThreshold: 10, Price: 120}
+	freeStep := models.PricingRule{Name: "Free", Type: models.PricingRuleDemandStep, IsActive: true, SoldThreshold: 10, Price: 0}
+	inactive := group
+	inactive.IsActive = false
+
+	tests := []struct {
+		name      string
+		quantity  int
+		sold      int
+		rules     []models.PricingRule
+		unitPrice int
+		applied   []int
+		total     int
+	}{
+		{name: "no rules", quantity: 3, total: 300, unitPrice: 100},
+		{name: "group below minimum", quantity: 4, rules: []models.PricingRule{group}, unitPrice: 100, total: 400},
+		{name: "group at minimum", quantity: 5, rules: []models.PricingRule{group}, unitPrice: 100, applied: []int{-50}, total: 450},
+		{name: "inactive rule ignored", quantity: 5, rules: []models.PricingRule{inactive}, unitPrice: 100, total: 500},
+		{name: "bundle incomplete set", quantity: 2, rules: []models.PricingRule{bundle}, unitPrice: 100, total: 200},
+		{name: "bundle two sets", quantity: 7, rules: []models.PricingRule{bundle}, unitPrice: 100, applied: []int{-200}, total: 500},
+		{name: "step below threshold", quantity: 2, sold: 9, rules: []models.PricingRule{step}, unitPrice: 100, total: 200},
+		{name: "step at threshold", quantity: 2, sold: 10, rules: []models.PricingRule{step}, unitPrice: 120, applied: []int{40}, total: 240},
+		{name: "free step skips bundle", quantity: 3, sold: 10, rules: []models.PricingRule{freeStep, bundle}, unitPrice: 0, applied: []int{-300}, total: 0},
+		{
+			name:      "step then bundle then group",
+			quantity:  6,
+			sold:      10,
+			rules:     []models.PricingRule{group, bundle, step},
+			unitPrice: 120,
+			applied:   []int{120, -240, -48},
+			total:     432,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := Evaluate(100, tt.quantity, tt.sold, tt.rules)
+			if quote.UnitPrice != tt.unitPrice {
+				t.Errorf("unit price = %d, want %d", quote.UnitPrice, tt.unitPrice)
+			}
+			if quote.Subtotal != 100*tt.quantity {
+				t.Errorf("subtotal = %d, want %d", quote.Subtotal, 100*tt.quantity)
+			}
+			if quote.Total != tt.total {
+				t.Errorf("total = %d, want %d", quote.Total, tt.total)
+			}
+			if len(quote.Applied) != len(tt.applied) {
+				t.Fatalf("applied %d rules, want %d: %+v", len(quote.Applied), len(tt.applied), quote.Applied)
+			}
+			for i, amount := range tt.applied {
+				if quote.Applied[i].Amount != amount {
+					t.Errorf("applied[%d] (%s) = %d, want %d", i, quote.Applied[i].Name, quote.Applied[i].Amount, amount)
+				}
+			}
+		})
+	}
+}
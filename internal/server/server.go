@@ -3,9 +3,12 @@ package server
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/farellandr/spoticket/config"
 	"github.com/farellandr/spoticket/internal/handlers"
+	"github.com/farellandr/spoticket/internal/helpers"
+	"github.com/farellandr/spoticket/internal/jobs"
 	"github.com/farellandr/spoticket/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/xendit/xendit-go/v6"
@@ -33,9 +36,33 @@ func Start() error {
 		return fmt.Errorf("failed to initialize Xendit client: %v", err)
 	}
 
+	walletCfg, err := config.LoadWalletConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load wallet config: %v", err)
+	}
+
+	walletIssuer, err := config.InitWalletIssuer(walletCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize wallet issuer: %v", err)
+	}
+
+	smsCfg, err := config.LoadSMSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load SMS config: %v", err)
+	}
+
+	smsSender, err := config.InitSMSSender(smsCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize SMS sender: %v", err)
+	}
+
 	r := gin.Default()
 
-	setupRoutes(r, db, xnd)
+	checkInHub := helpers.NewCheckInHub()
+
+	setupRoutes(r, db, xnd, checkInHub, walletIssuer, smsSender)
+
+	jobs.Start(db, 10*time.Second)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -44,9 +71,12 @@ func Start() error {
 	return r.Run(":" + port)
 }
 
-func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient) {
+func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient, checkInHub *helpers.CheckInHub, walletIssuer *helpers.WalletIssuer, smsSender *helpers.SMSSender) {
 	r.Use(middleware.DatabaseMiddleware(db))
 	r.Use(middleware.XenditMiddleware(xnd))
+	r.Use(middleware.CheckInHubMiddleware(checkInHub))
+	r.Use(middleware.WalletMiddleware(walletIssuer))
+	r.Use(middleware.SMSMiddleware(smsSender))
 
 	public := r.Group("/v1")
 	{
@@ -71,11 +101,26 @@ func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient) {
 			eventPublic.GET("", handlers.ListEvents)
 			eventPublic.GET("/:id", handlers.GetEvent)
 			eventPublic.GET("/:id/banner", handlers.StreamEventBanner)
+			eventPublic.GET("/:id/seatmap", handlers.GetEventSeatMap)
+			eventPublic.GET("/:id/products", handlers.ListEventProducts)
+		}
+
+		venuePublic := public.Group("/venues")
+		{
+			venuePublic.GET("", handlers.ListVenues)
+			venuePublic.GET("/:id", handlers.GetVenue)
+			venuePublic.GET("/:id/photos/:photoId", handlers.StreamVenuePhoto)
+		}
+
+		seriesPublic := public.Group("/series")
+		{
+			seriesPublic.GET("/:id", handlers.GetSeries)
 		}
 
 		ticketPublic := public.Group("/tickets")
 		{
 			ticketPublic.GET("/:id", handlers.GetTicket)
+			ticketPublic.GET("/:id/quote", handlers.QuoteTicket)
 		}
 
 		couponPublic := public.Group("/coupons")
@@ -84,6 +129,11 @@ func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient) {
 			couponPublic.GET("/:id", handlers.GetCoupon)
 		}
 
+		resalePublic := public.Group("/resale")
+		{
+			resalePublic.GET("", handlers.ListResaleListings)
+		}
+
 		paymentPublic := public.Group("/payments")
 		{
 			paymentPublic.POST("/notification", handlers.PaymentNotification)
@@ -99,6 +149,8 @@ func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient) {
 			profileProtected.PUT("/update", handlers.EditProfile)
 			profileProtected.PUT("/change-password", handlers.ChangePassword)
 			profileProtected.DELETE("/remove-picture", handlers.RemoveProfilePicture)
+			profileProtected.POST("/phone/verification", handlers.RequestPhoneVerification)
+			profileProtected.POST("/phone/verification/confirm", handlers.ConfirmPhoneVerification)
 		}
 
 		categoryProtected := protected.Group("/categories")
@@ -111,8 +163,44 @@ func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient) {
 		eventProtected := protected.Group("/events")
 		{
 			eventProtected.POST("", handlers.CreateEvent)
+			eventProtected.GET("/mine", handlers.ListMyEvents)
+			eventProtected.GET("/:id/preview", handlers.PreviewEvent)
+			eventProtected.POST("/:id/publish", handlers.PublishEvent)
+			eventProtected.POST("/:id/unschedule", handlers.UnscheduleEvent)
+			eventProtected.POST("/:id/cancel", handlers.CancelEvent)
 			eventProtected.PUT("/:id", handlers.UpdateEvent)
 			eventProtected.DELETE("/:id", handlers.DeleteEvent)
+			eventProtected.GET("/:id/checkins/stats", handlers.GetCheckInStats)
+			eventProtected.GET("/:id/checkins/stream", handlers.StreamCheckIns)
+			eventProtected.PUT("/:id/seatmap", handlers.SaveEventSeatMap)
+			eventProtected.PUT("/:id/seats/tiers", handlers.AssignSeatTier)
+			eventProtected.POST("/:id/products", handlers.CreateProduct)
+			eventProtected.PUT("/:id/products/:productId", handlers.UpdateProduct)
+			eventProtected.DELETE("/:id/products/:productId", handlers.DeleteProduct)
+			eventProtected.GET("/:id/access-codes", handlers.ListAccessCodes)
+			eventProtected.POST("/:id/access-codes", handlers.CreateAccessCode)
+			eventProtected.PUT("/:id/access-codes/:codeId", handlers.UpdateAccessCode)
+			eventProtected.DELETE("/:id/access-codes/:codeId", handlers.DeleteAccessCode)
+			eventProtected.GET("/:id/waiting-room", handlers.GetWaitingRoom)
+			eventProtected.PUT("/:id/waiting-room", handlers.SaveWaitingRoom)
+			eventProtected.POST("/:id/queue", handlers.JoinQueue)
+			eventProtected.GET("/:id/queue", handlers.GetQueueStatus)
+		}
+
+		venueProtected := protected.Group("/venues")
+		{
+			venueProtected.POST("", handlers.CreateVenue)
+			venueProtected.PUT("/:id", handlers.UpdateVenue)
+			venueProtected.DELETE("/:id", handlers.DeleteVenue)
+			venueProtected.DELETE("/:id/photos/:photoId", handlers.DeleteVenuePhoto)
+			venueProtected.PUT("/:id/seatmap", handlers.SaveVenueSeatMap)
+		}
+
+		seriesProtected := protected.Group("/series")
+		{
+			seriesProtected.POST("", handlers.CreateSeries)
+			seriesProtected.POST("/:id/publish", handlers.PublishSeries)
+			seriesProtected.POST("/:id/occurrences/:eventId/cancel", handlers.CancelSeriesOccurrence)
 		}
 
 		ticketProtected := protected.Group("/tickets")
@@ -121,11 +209,19 @@ func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient) {
 			ticketProtected.PUT("/:id", handlers.UpdateTicket)
 			ticketProtected.DELETE("/:id", handlers.DeleteTicket)
 			ticketProtected.POST("/validate", handlers.ValidateTicket)
+			ticketProtected.POST("/:id/waitlist", handlers.JoinWaitlist)
+			ticketProtected.GET("/:id/waitlist", handlers.GetWaitlistStatus)
+			ticketProtected.DELETE("/:id/waitlist", handlers.LeaveWaitlist)
+			ticketProtected.GET("/:id/pricing-rules", handlers.ListPricingRules)
+			ticketProtected.POST("/:id/pricing-rules", handlers.CreatePricingRule)
+			ticketProtected.PUT("/:id/pricing-rules/:ruleId", handlers.UpdatePricingRule)
+			ticketProtected.DELETE("/:id/pricing-rules/:ruleId", handlers.DeletePricingRule)
 		}
 
 		couponProtected := protected.Group("/coupons")
 		{
 			couponProtected.POST("", handlers.CreateCoupon)
+			couponProtected.GET("/mine", handlers.ListMyCoupons)
 			couponProtected.POST("/claim", handlers.ClaimCoupon)
 			couponProtected.PUT("/:id", handlers.UpdateCoupon)
 			couponProtected.DELETE("/:id", handlers.DeleteCoupon)
@@ -136,9 +232,38 @@ func setupRoutes(r *gin.Engine, db *gorm.DB, xnd *xendit.APIClient) {
 			paymentProtected.POST("", handlers.CreatePaymentLink)
 		}
 
+		resaleProtected := protected.Group("/resale")
+		{
+			resaleProtected.POST("", handlers.CreateResaleListing)
+			resaleProtected.DELETE("/:id", handlers.CancelResaleListing)
+			resaleProtected.POST("/:id/buy", handlers.BuyResaleListing)
+		}
+
 		purchaseProtected := protected.Group("/purchases")
 		{
+			purchaseProtected.GET("", handlers.ListMyPurchases)
+			purchaseProtected.GET(":purchaseId", handlers.GetPurchase)
+			purchaseProtected.PUT(":purchaseId/attendee", handlers.AssignAttendee)
 			purchaseProtected.GET(":purchaseId/qr", handlers.GenerateTicketQR)
+			purchaseProtected.GET(":purchaseId/wallet/apple", handlers.GenerateApplePass)
+			purchaseProtected.GET(":purchaseId/wallet/google", handlers.GenerateGooglePassLink)
+			purchaseProtected.GET(":purchaseId/transfers", handlers.ListPurchaseTransfers)
+			purchaseProtected.POST(":purchaseId/transfers", handlers.InitiateTransfer)
+		}
+
+		voucherProtected := protected.Group("/vouchers")
+		{
+			voucherProtected.GET("", handlers.ListMyVouchers)
+			voucherProtected.POST("/validate", handlers.ValidateVoucher)
+			voucherProtected.GET("/:voucherId/qr", handlers.GenerateVoucherQR)
+		}
+
+		transferProtected := protected.Group("/transfers")
+		{
+			transferProtected.GET("", handlers.ListTransfers)
+			transferProtected.POST("/:id/accept", handlers.AcceptTransfer)
+			transferProtected.POST("/:id/decline", handlers.DeclineTransfer)
+			transferProtected.POST("/:id/cancel", handlers.CancelTransfer)
 		}
 	}
 }
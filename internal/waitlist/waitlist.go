@@ -0,0 +1,135 @@
+package waitlist
+
+import (
+	"time"
+
+	"github.com/farellandr/spoticket/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OfferDuration is how long a waitlisted buyer has to pay for an offer before
+// it rolls to the next person.
+const OfferDuration = 30 * time.Minute
+
+// Available returns how many tickets of a tier can still be bought by userID:
+// the limit minus sold tickets, unpaid checkouts and other users' open offers.
+// The user's own open offer, if any, is returned so checkout can consume it.
+func Available(db *gorm.DB, ticket *models.Ticket, userID uuid.UUID) (int, *models.WaitlistEntry, error) {
+	reserved, err := reservedCount(db, ticket.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var offer models.WaitlistEntry
+	err = db.Where("ticket_id = ? AND user_id = ? AND status = ? AND offer_expires_at > ?", ticket.ID, userID, models.WaitlistStatusOffered, time.Now()).First(&offer).Error
+	if err == gorm.ErrRecordNotFound {
+		return max(ticket.Limit-reserved, 0), nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return max(ticket.Limit-reserved+offer.Quantity, 0), &offer, nil
+}
+
+func reservedCount(db *gorm.DB, ticketID uuid.UUID) (int, error) {
+	var sold, pending, offered int64
+	if err := db.Model(&models.Purchase{}).Where("ticket_id = ?", ticketID).Count(&sold).Error; err != nil {
+		return 0, err
+	}
+	// Resale checkouts move existing purchases and don't use up inventory.
+	err := db.Model(&models.Payment{}).Select("COALESCE(SUM(quantity), 0)").
+		Where("ticket_id = ? AND status = ? AND transaction_id NOT LIKE ?", ticketID, "PENDING", "RSL-%").
+		Scan(&pending).Error
+	if err != nil {
+		return 0, err
+	}
+	err = db.Model(&models.WaitlistEntry{}).Select("COALESCE(SUM(quantity), 0)").
+		Where("ticket_id = ? AND status = ? AND offer_expires_at > ?", ticketID, models.WaitlistStatusOffered, time.Now()).
+		Scan(&offered).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(sold + pending + offered), nil
+}
+
+// OfferFreedTickets hands freed inventory to the front of the ticket's
+// waitlist. Entries are offered strictly in the order they joined, so a large
+// request at the front holds back smaller ones behind it.
+func OfferFreedTickets(db *gorm.DB, ticketID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var ticket models.Ticket
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Event").First(&ticket, "id = ?", ticketID).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		salesEnd := ticket.Event.StartTime
+		if ticket.SalesEnd != nil {
+			salesEnd = *ticket.SalesEnd
+		}
+		if ticket.Event.Status != models.EventStatusPublished || !now.Before(salesEnd) {
+			return nil
+		}
+
+		reserved, err := reservedCount(tx, ticket.ID)
+		if err != nil {
+			return err
+		}
+		available := ticket.Limit - reserved
+
+		var entries []models.WaitlistEntry
+		err = tx.Where("ticket_id = ? AND status = ?", ticket.ID, models.WaitlistStatusWaiting).Order("created_at").Find(&entries).Error
+		if err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(OfferDuration)
+		for _, entry := range entries {
+			if entry.Quantity > available {
+				break
+			}
+			err := tx.Model(&entry).Updates(map[string]interface{}{
+				"status":           models.WaitlistStatusOffered,
+				"offered_at":       now,
+				"offer_expires_at": expiresAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+			available -= entry.Quantity
+		}
+		return nil
+	})
+}
+
+// ExpireOffers closes unpaid offers. Their tickets go back to the waitlist on
+// the next OfferAll.
+func ExpireOffers(db *gorm.DB) error {
+	return db.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND offer_expires_at <= ?", models.WaitlistStatusOffered, time.Now()).
+		Update("status", models.WaitlistStatusExpired).Error
+}
+
+// OfferAll runs OfferFreedTickets for every ticket with people waiting, which
+// catches inventory freed outside the payment and ticket handlers.
+func OfferAll(db *gorm.DB) error {
+	var ticketIDs []uuid.UUID
+	err := db.Model(&models.WaitlistEntry{}).Where("status = ?", models.WaitlistStatusWaiting).
+		Distinct().Pluck("ticket_id", &ticketIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, ticketID := range ticketIDs {
+		if err := OfferFreedTickets(db, ticketID); err != nil {
+			return err
+		}
+	}
+	return nil
+}